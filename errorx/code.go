@@ -48,6 +48,25 @@ const (
 	JsonParseCodeErr
 	Base64ParseCodeErr
 	PemParseCodeErr
+	GinxRequestTimeoutErr
+	GinxIdempotencyKeyErr
+	GinxIdempotencyConflictErr
+	GinxIdempotencyMismatchErr
+	GinxIdempotencyStoreErr
+	GinxPanicRecoverErr
+	GinxCSRFTokenErr
+	GinxWsOriginErr
+	GinxWsSendQueueFullErr
+	GinxWsConnClosedErr
+	GinxSSEClosedErr
+	GinxParamBindErr
+	GinxUploadMalformedErr
+	GinxUploadTooLargeErr
+	GinxUploadTypeErr
+	GinxUploadStorageErr
+	GinxApiVersionErr
+	GinxLogLevelErr
+	GinxSSEFieldErr
 )
 
 //Gateway类错误码列表
@@ -14,9 +14,31 @@ var (
 	WithMessagef = errors.WithMessagef
 )
 
+// HeaderRequestTimeout 上下游透传的剩余超时时间(毫秒), request包发出、TimeoutMiddleware读取
+const HeaderRequestTimeout = "X-Request-Timeout"
+
 var (
 	ErrMethodNotAllow = New500Response(methodNotFoundErr, "method not allow")
 	ErrNotFound       = New500Response(routerNotFoundErr, "router not found")
+	ErrRequestTimeout = New504Response(GinxRequestTimeoutErr, "request timeout")
+	ErrPanicRecover   = New500Response(GinxPanicRecoverErr, "internal server error")
+	ErrCSRFToken      = New403Response(GinxCSRFTokenErr, "invalid csrf token")
+
+	ErrWsOrigin        = New403Response(GinxWsOriginErr, "websocket origin not allowed")
+	ErrWsSendQueueFull = New500Response(GinxWsSendQueueFullErr, "websocket send queue full")
+	ErrWsConnClosed    = New500Response(GinxWsConnClosedErr, "websocket connection closed")
+	ErrSSEClosed       = New500Response(GinxSSEClosedErr, "sse stream closed")
+	ErrSSEField        = New500Response(GinxSSEFieldErr, "sse id, event or comment contains a line break")
+
+	ErrUploadMalformed = New400Response(GinxUploadMalformedErr, "malformed multipart request")
+	ErrUploadTooLarge  = NewErrResponse(413, GinxUploadTooLargeErr, "upload size exceeds limit")
+	ErrUploadType      = NewErrResponse(415, GinxUploadTypeErr, "upload file type not allowed")
+
+	ErrApiVersion = New404Response(GinxApiVersionErr, "api version not found")
+
+	ErrIdempotencyKeyMissing = New400Response(GinxIdempotencyKeyErr, "Idempotency-Key header required")
+	ErrIdempotencyConflict   = NewErrResponse(409, GinxIdempotencyConflictErr, "request with the same Idempotency-Key is in progress")
+	ErrIdempotencyMismatch   = NewErrResponse(422, GinxIdempotencyMismatchErr, "Idempotency-Key reused with a different request body")
 
 	ErrJwtTokenMalformed   = New401Response(JwtTokenMalformedErr, "That's not even a token of jwt")
 	ErrJwtTokenExpired     = New401Response(JwtTokenExpiredErr, "Token of jwt is expired")
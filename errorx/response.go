@@ -86,6 +86,10 @@ func New500Response(code int, msg string, args ...interface{}) error {
 	return NewErrResponse(500, code, msg, args...)
 }
 
+func New504Response(code int, msg string, args ...interface{}) error {
+	return NewErrResponse(504, code, msg, args...)
+}
+
 func Wrap400Response(err error, code int, msg string, args ...interface{}) error {
 	return WrapErrResponse(err, 400, code, msg, args...)
 }
@@ -105,3 +109,7 @@ func Wrap404Response(err error, code int, msg string, args ...interface{}) error
 func Wrap500Response(err error, code int, msg string, args ...interface{}) error {
 	return WrapErrResponse(err, 500, code, msg, args...)
 }
+
+func Wrap504Response(err error, code int, msg string, args ...interface{}) error {
+	return WrapErrResponse(err, 504, code, msg, args...)
+}
@@ -0,0 +1,354 @@
+package ginx
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/textproto"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/ytf606/golibs/errorx"
+	"github.com/ytf606/golibs/ginx/validate"
+)
+
+// Bind参数来源, 对应ValidError.Source
+const (
+	SourceUri    = "uri"
+	SourceHeader = "header"
+	SourceQuery  = "query"
+	SourceForm   = "form"
+	SourceJson   = "json"
+)
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+)
+
+// Bind 一次性从uri、header、query、form和json body绑定到obj并统一校验一次
+// 后绑定的来源覆盖先绑定的: query < form/json < header < uri, 路径参数不会被body中的同名字段覆盖
+// uri/header字段需显式写tag, query/form与gin一致, 未写form tag时使用字段名, 支持form:"page,default=1"
+// 失败时返回ErrValidate包装的validate.ValidErrors, 包含所有类型转换错误和其余字段的校验错误, 每项带有字段来源
+// obj不是结构体指针属于调用方错误, 返回500而非400
+func Bind(c *gin.Context, obj interface{}, code int) error {
+	rv := reflect.ValueOf(obj)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return errorx.Wrap500Response(errorx.Errorf("bind obj must be a struct pointer, got %T", obj), code, "")
+	}
+	v := rv.Elem()
+	b := &binder{root: v.Type(), failed: make(map[string]bool)}
+	ns := v.Type().Name()
+
+	req := c.Request
+	b.mapValues(v, ns, "form", SourceQuery, req.URL.Query(), false)
+	bodySource, jsonBroken := "", false
+	switch c.ContentType() {
+	case binding.MIMEPOSTForm, binding.MIMEMultipartPOSTForm:
+		bodySource = SourceForm
+		if err := req.ParseMultipartForm(32 << 20); err != nil && err != http.ErrNotMultipart {
+			b.errs = append(b.errs, &validate.ValidError{Key: SourceForm, Source: SourceForm, Msg: err.Error()})
+		} else {
+			b.mapValues(v, ns, "form", SourceForm, req.PostForm, false)
+		}
+	case binding.MIMEJSON:
+		if req.Body != nil && req.ContentLength != 0 {
+			bodySource = SourceJson
+			decoder := json.NewDecoder(req.Body)
+			if binding.EnableDecoderUseNumber {
+				decoder.UseNumber()
+			}
+			if binding.EnableDecoderDisallowUnknownFields {
+				decoder.DisallowUnknownFields()
+			}
+			// 类型错误时decoder会继续解析其余字段, 其他错误时body中的字段都未绑定
+			if err := decoder.Decode(obj); err != nil && err != io.EOF {
+				if te, ok := err.(*json.UnmarshalTypeError); ok && te.Field != "" {
+					b.fail(ns+"."+te.Field, te.Field, SourceJson, err)
+				} else {
+					jsonBroken = true
+					b.errs = append(b.errs, &validate.ValidError{Key: SourceJson, Source: SourceJson, Msg: err.Error()})
+				}
+			}
+		}
+	}
+	b.mapValues(v, ns, "header", SourceHeader, req.Header, true)
+	if len(c.Params) > 0 {
+		params := make(map[string][]string, len(c.Params))
+		for _, p := range c.Params {
+			params[p.Key] = []string{p.Value}
+		}
+		b.mapValues(v, ns, "uri", SourceUri, params, false)
+	}
+
+	if binding.Validator == nil {
+		return b.result(code)
+	}
+	err := binding.Validator.ValidateStruct(obj)
+	if err == nil {
+		return b.result(code)
+	}
+	verrs, ok := err.(validate.ValidationErrors)
+	if !ok {
+		if len(b.errs) > 0 {
+			return ErrValidate(b.errs, code)
+		}
+		return ErrValidate(err, code, Locales(c)...)
+	}
+	trans := validate.GetTrans(Locales(c)...)
+	for _, fe := range verrs {
+		path := validate.FieldPath(v.Type(), fe.StructNamespace())
+		source := fieldSource(v.Type(), fe.StructNamespace(), bodySource)
+		// 类型转换失败的字段已报错, 不再重复报校验错误
+		if b.failed[path] || (jsonBroken && source == SourceJson) {
+			continue
+		}
+		b.errs = append(b.errs, &validate.ValidError{
+			Key:    fe.Namespace(),
+			Msg:    fe.Translate(trans),
+			Source: source,
+			Path:   path,
+			Tag:    fe.Tag(),
+			Param:  fe.Param(),
+		})
+	}
+	return b.result(code)
+}
+
+// BindCheck Bind失败时直接返回错误响应
+func BindCheck(c *gin.Context, obj interface{}, code int) error {
+	if err := Bind(c, obj, code); err != nil {
+		ErrResponse(c, err)
+		return err
+	}
+	return nil
+}
+
+// binder 收集各来源的类型转换错误, 记录转换失败的字段
+type binder struct {
+	root   reflect.Type
+	errs   validate.ValidErrors
+	failed map[string]bool
+}
+
+func (b *binder) result(code int) error {
+	if len(b.errs) == 0 {
+		return nil
+	}
+	return ErrValidate(b.errs, code)
+}
+
+// fail 记录字段的类型转换错误, path为按json tag命名的字段路径
+func (b *binder) fail(namespace, path, source string, err error) {
+	b.failed[path] = true
+	b.errs = append(b.errs, &validate.ValidError{Key: namespace, Source: source, Path: path, Msg: err.Error()})
+}
+
+// fieldSource 按顶层字段的tag判断校验失败字段的来源
+func fieldSource(t reflect.Type, namespace, bodySource string) string {
+	query := SourceQuery
+	if bodySource == SourceForm {
+		query = SourceForm
+	}
+	// namespace形如Req.Base.Name, 第一段是结构体名
+	parts := strings.Split(namespace, ".")
+	for _, part := range parts[1:] {
+		if i := strings.IndexByte(part, '['); i >= 0 {
+			part = part[:i]
+		}
+		f, ok := t.FieldByName(part)
+		if !ok {
+			break
+		}
+		if f.Anonymous {
+			t = f.Type
+			for t.Kind() == reflect.Ptr {
+				t = t.Elem()
+			}
+			continue
+		}
+		jsonTag := f.Tag.Get("json")
+		switch {
+		case f.Tag.Get("uri") != "":
+			return SourceUri
+		case f.Tag.Get("header") != "":
+			return SourceHeader
+		case bodySource == SourceJson && jsonTag != "-" && (jsonTag != "" || f.Tag.Get("form") == ""):
+			return SourceJson
+		}
+		return query
+	}
+	if bodySource != "" {
+		return bodySource
+	}
+	return query
+}
+
+// mapValues 按tag从values中取值赋给字段, 匿名结构体字段展开, ns为v的结构体命名空间
+// 转换失败的字段逐个记录, 不影响其余字段
+func (b *binder) mapValues(v reflect.Value, ns, tag, source string, values map[string][]string, header bool) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		fv := v.Field(i)
+		opts := strings.Split(sf.Tag.Get(tag), ",")
+		name := opts[0]
+		if name == "-" {
+			continue
+		}
+		if sf.Anonymous && name == "" {
+			if fv.Kind() == reflect.Ptr && fv.Type().Elem().Kind() == reflect.Struct {
+				if fv.IsNil() {
+					if !fv.CanSet() {
+						continue
+					}
+					fv.Set(reflect.New(fv.Type().Elem()))
+				}
+				fv = fv.Elem()
+			}
+			if fv.Kind() == reflect.Struct {
+				b.mapValues(fv, ns+"."+sf.Name, tag, source, values, header)
+				continue
+			}
+		}
+		if sf.PkgPath != "" || !fv.CanSet() {
+			continue
+		}
+		if name == "" {
+			// 与gin一致, query/form未写tag时使用字段名, 已声明为uri/header的字段除外
+			if tag != "form" || sf.Tag.Get("uri") != "" || sf.Tag.Get("header") != "" {
+				continue
+			}
+			name = sf.Name
+		}
+		if header {
+			name = textproto.CanonicalMIMEHeaderKey(name)
+		}
+		vals, ok := values[name]
+		if !ok || len(vals) == 0 {
+			def, has := defaultValue(opts[1:])
+			if !has {
+				continue
+			}
+			vals = []string{def}
+		}
+		if err := setValues(fv, sf, vals); err != nil {
+			namespace := ns + "." + sf.Name
+			b.fail(namespace, validate.FieldPath(b.root, namespace), source, errorx.Errorf("%s: %v", name, err))
+		}
+	}
+}
+
+func defaultValue(opts []string) (string, bool) {
+	for _, opt := range opts {
+		if strings.HasPrefix(opt, "default=") {
+			return strings.TrimPrefix(opt, "default="), true
+		}
+	}
+	return "", false
+}
+
+func setValues(fv reflect.Value, sf reflect.StructField, vals []string) error {
+	switch fv.Kind() {
+	case reflect.Slice:
+		s := reflect.MakeSlice(fv.Type(), len(vals), len(vals))
+		for i, val := range vals {
+			if err := setValue(s.Index(i), sf, val); err != nil {
+				return err
+			}
+		}
+		fv.Set(s)
+		return nil
+	case reflect.Array:
+		if len(vals) != fv.Len() {
+			return errorx.Errorf("%q is not valid value for %s", vals, fv.Type())
+		}
+		for i, val := range vals {
+			if err := setValue(fv.Index(i), sf, val); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return setValue(fv, sf, vals[0])
+}
+
+func setValue(fv reflect.Value, sf reflect.StructField, val string) error {
+	switch fv.Type() {
+	case durationType:
+		if val == "" {
+			val = "0"
+		}
+		d, err := time.ParseDuration(val)
+		if err != nil {
+			return err
+		}
+		fv.SetInt(int64(d))
+		return nil
+	case timeType:
+		if val == "" {
+			fv.Set(reflect.Zero(timeType))
+			return nil
+		}
+		layout := sf.Tag.Get("time_format")
+		if layout == "" {
+			layout = time.RFC3339
+		}
+		tm, err := time.ParseInLocation(layout, val, time.Local)
+		if err != nil {
+			return err
+		}
+		fv.Set(reflect.ValueOf(tm))
+		return nil
+	}
+
+	switch fv.Kind() {
+	case reflect.Ptr:
+		p := reflect.New(fv.Type().Elem())
+		if err := setValue(p.Elem(), sf, val); err != nil {
+			return err
+		}
+		fv.Set(p)
+		return nil
+	case reflect.String:
+		fv.SetString(val)
+		return nil
+	}
+	if val == "" {
+		fv.Set(reflect.Zero(fv.Type()))
+		return nil
+	}
+	switch fv.Kind() {
+	case reflect.Bool:
+		b, err := strconv.ParseBool(val)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(val, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(val, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(val, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(n)
+	default:
+		return errorx.Errorf("unsupported type %s", fv.Type())
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package ginx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/ytf606/golibs/errorx"
+	"github.com/ytf606/golibs/ginx/validate"
+)
+
+type bindReq struct {
+	Id     int64  `uri:"id" binding:"required,gt=0"`
+	Tenant string `header:"X-Tenant-Id" binding:"required"`
+	Page   int    `form:"page,default=1"`
+	Name   string `json:"name" binding:"required"`
+}
+
+func TestBind(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var req bindReq
+	var bindErr error
+	r := gin.New()
+	r.PUT("/user/:id", func(c *gin.Context) {
+		req = bindReq{}
+		bindErr = Bind(c, &req, 1001)
+	})
+	do := func(target, tenant, body string) {
+		hr := httptest.NewRequest(http.MethodPut, target, strings.NewReader(body))
+		hr.Header.Set("Content-Type", "application/json")
+		if tenant != "" {
+			hr.Header.Set("X-Tenant-Id", tenant)
+		}
+		r.ServeHTTP(httptest.NewRecorder(), hr)
+	}
+
+	do("/user/7", "t1", `{"name":"tom","id":9}`)
+	assert.Nil(t, bindErr)
+	assert.Equal(t, bindReq{Id: 7, Tenant: "t1", Page: 1, Name: "tom"}, req)
+
+	do("/user/0?page=3", "", `{}`)
+	res := errorx.UnWrapResponse(bindErr)
+	if assert.NotNil(t, res) {
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+		assert.Equal(t, 1001, res.Code)
+		errs, ok := res.Err.(validate.ValidErrors)
+		assert.True(t, ok)
+		sources := make(map[string]string)
+		paths := make(map[string]bool)
+		for _, e := range errs {
+			sources[e.Key] = e.Source
+			paths[e.Path] = true
+		}
+		assert.Equal(t, map[string]bool{"id": true, "X-Tenant-Id": true, "name": true}, paths)
+		assert.Equal(t, map[string]string{
+			"bindReq.Id":     SourceUri,
+			"bindReq.Tenant": SourceHeader,
+			"bindReq.Name":   SourceJson,
+		}, sources)
+	}
+	assert.Equal(t, 3, req.Page)
+
+	do("/user/abc", "t1", `{"name":"tom"}`)
+	res = errorx.UnWrapResponse(bindErr)
+	if assert.NotNil(t, res) {
+		errs := res.Err.(validate.ValidErrors)
+		assert.Len(t, errs, 1)
+		assert.Equal(t, SourceUri, errs[0].Source)
+	}
+
+	// 类型转换错误与其余字段的校验错误一起返回
+	do("/user/abc?page=x", "", `{"name":1}`)
+	res = errorx.UnWrapResponse(bindErr)
+	if assert.NotNil(t, res) {
+		errs := res.Err.(validate.ValidErrors)
+		sources := make(map[string]string)
+		for _, e := range errs {
+			sources[e.Path] = e.Source
+		}
+		assert.Equal(t, map[string]string{
+			"id":          SourceUri,
+			"X-Tenant-Id": SourceHeader,
+			"page":        SourceQuery,
+			"name":        SourceJson,
+		}, sources)
+		assert.Len(t, errs, 4)
+	}
+
+	do("/user/7", "t1", `{"name":`)
+	res = errorx.UnWrapResponse(bindErr)
+	if assert.NotNil(t, res) {
+		errs := res.Err.(validate.ValidErrors)
+		assert.Len(t, errs, 1)
+		assert.Equal(t, SourceJson, errs[0].Source)
+	}
+}
+
+func TestBindInvalidObj(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	for _, obj := range []interface{}{bindReq{}, new(int), nil} {
+		res := errorx.UnWrapResponse(Bind(c, obj, 1001))
+		if assert.NotNil(t, res) {
+			assert.Equal(t, http.StatusInternalServerError, res.StatusCode)
+		}
+	}
+}
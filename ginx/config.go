@@ -3,6 +3,8 @@ package ginx
 var (
 	defaultHttpStatus int
 	isMsgKey          bool
+	langQuery         = "lang"
+	validateDetail    bool
 )
 
 func SetDefaultHttpStatus(httpStatus int) {
@@ -12,3 +14,26 @@ func SetDefaultHttpStatus(httpStatus int) {
 func SetMsgKey() {
 	isMsgKey = true
 }
+
+// DefaultHttpStatus SetDefaultHttpStatus设置的状态码, 未设置时为0
+func DefaultHttpStatus() int {
+	return defaultHttpStatus
+}
+
+// MsgKey 响应中错误信息的字段名
+func MsgKey() string {
+	if isMsgKey {
+		return "msg"
+	}
+	return "message"
+}
+
+// SetLangQuery 设置指定校验提示语言的query参数名, 默认lang, 为空时只使用Accept-Language
+func SetLangQuery(key string) {
+	langQuery = key
+}
+
+// SetValidateDetail 校验失败时在响应data中返回字段级错误列表, 见validate.ValidError
+func SetValidateDetail() {
+	validateDetail = true
+}
@@ -22,6 +22,21 @@ func StdCtx(ctx *gin.Context) context.Context {
 	return c
 }
 
+// StdReqCtx - convert gin.Context to context.Context derived from the request context,
+// so downstream db, redis and http calls are cancelled with the client or by TimeoutMiddleware
+func StdReqCtx(ctx *gin.Context) context.Context {
+	c := context.Background()
+	if ctx.Request != nil {
+		c = ctx.Request.Context()
+	}
+
+	for k, v := range ctx.Keys {
+		c = context.WithValue(c, k, v)
+	}
+
+	return c
+}
+
 // GinHandler 将http.HandlerFunc转为gin.HandlerFunc
 func GinHandler(h http.HandlerFunc) gin.HandlerFunc {
 	return func(c *gin.Context) {
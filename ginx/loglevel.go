@@ -0,0 +1,62 @@
+package ginx
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ytf606/golibs/errorx"
+	"github.com/ytf606/golibs/logx"
+)
+
+// LogLevelRequest 调整日志级别, Tag为空时调整全局级别
+type LogLevelRequest struct {
+	Level string `json:"level" binding:"required"`
+	// Tag 日志writer的标签, 即LogConfig.Tag
+	Tag string `json:"tag"`
+	// TTL 到期后自动恢复, 如10m, 为空时不恢复
+	TTL string `json:"ttl"`
+}
+
+// LogLevelHandler 运行时查看和调整日志级别, 需注册在有鉴权的管理路由上
+// GET返回logx.LevelInfo, PUT/POST按LogLevelRequest调整, DELETE恢复配置的级别
+//
+//	admin.Any("/loglevel", ginx.LogLevelHandler())
+func LogLevelHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet:
+		case http.MethodDelete:
+			logx.ResetLevel()
+		case http.MethodPut, http.MethodPost:
+			var req LogLevelRequest
+			if err := ParseCheckJson(c, &req, errorx.GinxLogLevelErr); err != nil {
+				return
+			}
+			var ttl time.Duration
+			if req.TTL != "" {
+				var err error
+				if ttl, err = time.ParseDuration(req.TTL); err != nil {
+					ErrResponse(c, errorx.Wrap400Response(err, errorx.GinxLogLevelErr, "invalid ttl %s", req.TTL))
+					return
+				}
+			}
+			var err error
+			if req.Tag == "" {
+				err = logx.SetLevel(req.Level, ttl)
+			} else {
+				err = logx.SetTagLevel(req.Tag, req.Level, ttl)
+			}
+			if err != nil {
+				ErrResponse(c, errorx.Wrap400Response(err, errorx.GinxLogLevelErr, "%v", err))
+				return
+			}
+			logx.Wx(StdCtx(c), "[ginx_loglevel]", "log level changed by %s, tag:%s, level:%s, ttl:%s",
+				c.ClientIP(), req.Tag, req.Level, req.TTL)
+		default:
+			ErrResponse(c, errorx.ErrMethodNotAllow)
+			return
+		}
+		SuccResponse(c, logx.Levels())
+	}
+}
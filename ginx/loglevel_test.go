@@ -0,0 +1,40 @@
+package ginx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/ytf606/golibs/logx"
+)
+
+func TestLogLevelHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Any("/loglevel", LogLevelHandler())
+	defer logx.ResetLevel()
+
+	do := func(method, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/loglevel", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do(http.MethodPut, `{"level":"debug","ttl":"1m"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"level":"DEBUG"`)
+	assert.Contains(t, w.Body.String(), `"expires"`)
+
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodPut, `{"level":"verbose"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodPut, `{"level":"info","ttl":"soon"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodPut, `{}`).Code)
+
+	w = do(http.MethodDelete, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), `"expires"`)
+}
@@ -0,0 +1,268 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"crypto/sha1"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+)
+
+// CompressConfig 响应压缩与ETag配置
+type CompressConfig struct {
+	// Encodings 支持的编码, 按优先级排列, 可选br、gzip、deflate, 默认三者全部开启
+	Encodings []string
+	// MinLength 小于该长度的响应不压缩, 默认1024
+	MinLength int
+	// ContentTypes 允许压缩的Content-Type前缀, 默认json、text、javascript、xml
+	ContentTypes []string
+	// ETag 为200的JSON响应计算弱ETag, 命中If-None-Match时返回304
+	ETag bool
+	// SkipPaths 不压缩的路由, 以*结尾的按前缀匹配
+	SkipPaths []string
+}
+
+// DefaultCompressConfig -
+func DefaultCompressConfig() CompressConfig {
+	return CompressConfig{
+		Encodings: []string{"br", "gzip", "deflate"},
+		MinLength: 1024,
+		ContentTypes: []string{
+			"application/json",
+			"application/javascript",
+			"application/xml",
+			"text/",
+		},
+	}
+}
+
+var compressEncoders = map[string]func(w io.Writer) (io.WriteCloser, error){
+	"br": func(w io.Writer) (io.WriteCloser, error) {
+		return brotli.NewWriterLevel(w, brotli.DefaultCompression), nil
+	},
+	"gzip": func(w io.Writer) (io.WriteCloser, error) {
+		return gzip.NewWriterLevel(w, gzip.DefaultCompression)
+	},
+	"deflate": func(w io.Writer) (io.WriteCloser, error) {
+		return flate.NewWriter(w, flate.DefaultCompression)
+	},
+}
+
+// CompressMiddleware 按Accept-Encoding压缩响应, 可选计算ETag
+// 与Logger、IdempotencyMiddleware的bodyLogWriter配合时, 记录的始终是压缩前的body
+// handler调用Flush(如SSE)后切换为直接输出, 不再压缩
+func CompressMiddleware(config CompressConfig) gin.HandlerFunc {
+	def := DefaultCompressConfig()
+	if len(config.Encodings) == 0 {
+		config.Encodings = def.Encodings
+	}
+	if config.MinLength <= 0 {
+		config.MinLength = def.MinLength
+	}
+	if len(config.ContentTypes) == 0 {
+		config.ContentTypes = def.ContentTypes
+	}
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodHead || c.GetHeader("Upgrade") != "" ||
+			(len(config.SkipPaths) > 0 && matchRouter(config.SkipPaths, c.FullPath(), c.Request.URL.Path)) {
+			c.Next()
+			return
+		}
+		encoding := negotiateEncoding(c.GetHeader("Accept-Encoding"), config.Encodings)
+		if encoding == "" && !config.ETag {
+			c.Next()
+			return
+		}
+
+		cw := &compressWriter{ResponseWriter: c.Writer, buf: new(bytes.Buffer)}
+		c.Writer = cw
+		c.Next()
+		c.Writer = cw.ResponseWriter
+
+		if cw.passthrough {
+			return
+		}
+		cw.finish(c, config, encoding)
+	}
+}
+
+type compressWriter struct {
+	gin.ResponseWriter
+	buf         *bytes.Buffer
+	passthrough bool
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if w.passthrough {
+		return w.ResponseWriter.Write(b)
+	}
+	return w.buf.Write(b)
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	if w.passthrough {
+		return w.ResponseWriter.WriteString(s)
+	}
+	return w.buf.WriteString(s)
+}
+
+// WriteHeaderNow 延迟到finish时再提交header
+func (w *compressWriter) WriteHeaderNow() {
+	if w.passthrough {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+func (w *compressWriter) Written() bool {
+	return w.ResponseWriter.Written() || w.buf.Len() > 0
+}
+
+func (w *compressWriter) Size() int {
+	if w.passthrough {
+		return w.ResponseWriter.Size()
+	}
+	return w.buf.Len()
+}
+
+// Flush 流式输出时放弃压缩, 先写出已缓存内容
+func (w *compressWriter) Flush() {
+	if !w.passthrough {
+		w.passthrough = true
+		if w.buf.Len() > 0 {
+			w.ResponseWriter.Write(w.buf.Bytes())
+			w.buf.Reset()
+		}
+	}
+	w.ResponseWriter.Flush()
+}
+
+func (w *compressWriter) finish(c *gin.Context, config CompressConfig, encoding string) {
+	header := w.Header()
+	status := w.Status()
+	body := w.buf.Bytes()
+	contentType := filterFlags(header.Get("Content-Type"))
+
+	if config.ETag && status == http.StatusOK && strings.HasPrefix(contentType, "application/json") {
+		etag := weakETag(body)
+		header.Set("ETag", etag)
+		if etagMatch(c.GetHeader("If-None-Match"), etag) {
+			header.Del("Content-Type")
+			header.Del("Content-Length")
+			w.ResponseWriter.WriteHeader(http.StatusNotModified)
+			w.ResponseWriter.WriteHeaderNow()
+			return
+		}
+	}
+
+	if len(body) == 0 {
+		w.ResponseWriter.WriteHeaderNow()
+		return
+	}
+
+	if encoding == "" || len(body) < config.MinLength || header.Get("Content-Encoding") != "" ||
+		!allowContentType(config.ContentTypes, contentType) {
+		w.ResponseWriter.Write(body)
+		return
+	}
+
+	var compressed bytes.Buffer
+	enc, err := compressEncoders[encoding](&compressed)
+	if err == nil {
+		_, err = enc.Write(body)
+		if cerr := enc.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		w.ResponseWriter.Write(body)
+		return
+	}
+
+	header.Set("Content-Encoding", encoding)
+	header.Add("Vary", "Accept-Encoding")
+	header.Del("Content-Length")
+	if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		header.Set("ETag", "W/"+etag)
+	}
+
+	// bodyLogWriter记录压缩前内容, 压缩后的数据直接写给最内层writer
+	inner := w.ResponseWriter
+	for {
+		blw, ok := inner.(*bodyLogWriter)
+		if !ok {
+			break
+		}
+		blw.capture(body)
+		inner = blw.ResponseWriter
+	}
+	inner.Write(compressed.Bytes())
+}
+
+// negotiateEncoding 按Accept-Encoding的q值选择编码, q相同时按配置顺序
+func negotiateEncoding(accept string, encodings []string) string {
+	if accept == "" {
+		return ""
+	}
+	qs := make(map[string]float64)
+	for _, part := range strings.Split(accept, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, q := part, 1.0
+		if i := strings.Index(part, ";"); i >= 0 {
+			name = strings.TrimSpace(part[:i])
+			param := strings.TrimSpace(part[i+1:])
+			if strings.HasPrefix(param, "q=") {
+				if v, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64); err == nil {
+					q = v
+				}
+			}
+		}
+		qs[strings.ToLower(name)] = q
+	}
+
+	best, bestQ := "", 0.0
+	for _, e := range encodings {
+		if _, ok := compressEncoders[e]; !ok {
+			continue
+		}
+		q, ok := qs[e]
+		if !ok {
+			q, ok = qs["*"]
+		}
+		if ok && q > bestQ {
+			best, bestQ = e, q
+		}
+	}
+	return best
+}
+
+func weakETag(body []byte) string {
+	sum := sha1.Sum(body)
+	return `W/"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+// etagMatch If-None-Match按弱比较匹配
+func etagMatch(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	if strings.TrimSpace(ifNoneMatch) == "*" {
+		return true
+	}
+	target := strings.TrimPrefix(etag, "W/")
+	for _, tag := range strings.Split(ifNoneMatch, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(tag), "W/") == target {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompressMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	payload := strings.Repeat("golibs", 400)
+	r := gin.New()
+	r.Use(CompressMiddleware(CompressConfig{ETag: true}))
+	r.GET("/data", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"data": payload}) })
+	r.GET("/small", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+
+	do := func(target string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do("/data", map[string]string{"Accept-Encoding": "br;q=0.5, gzip"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", w.Header().Get("Vary"))
+	zr, err := gzip.NewReader(w.Body)
+	assert.Nil(t, err)
+	plain, _ := ioutil.ReadAll(zr)
+	assert.Contains(t, string(plain), payload)
+
+	etag := w.Header().Get("ETag")
+	assert.True(t, strings.HasPrefix(etag, `W/"`))
+	w = do("/data", map[string]string{"Accept-Encoding": "gzip", "If-None-Match": etag})
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Equal(t, 0, w.Body.Len())
+
+	w = do("/small", map[string]string{"Accept-Encoding": "gzip"})
+	assert.Equal(t, "", w.Header().Get("Content-Encoding"))
+	assert.Equal(t, "ok", w.Body.String())
+
+	w = do("/data", map[string]string{"Accept-Encoding": "identity"})
+	assert.Equal(t, "", w.Header().Get("Content-Encoding"))
+	assert.Contains(t, w.Body.String(), payload)
+}
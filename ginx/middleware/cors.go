@@ -1,14 +1,21 @@
 package middleware
 
 import (
+	"errors"
+	"sort"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/go-kratos/kratos/v2/config"
+	"github.com/ytf606/golibs/configx"
+	"github.com/ytf606/golibs/logx"
 )
 
 type Config struct {
+	// AllowAllOrigins 同时配置了AllowOrigins、AllowOriginFunc或Origins时不生效
 	AllowAllOrigins bool `ini:"allowAllOrigins"`
 
 	// AllowOrigins is a list of origins a cross-domain request can be executed from.
@@ -52,17 +59,194 @@ type Config struct {
 
 	// Allows usage of file:// schema (dangerous!) use it only when you 100% sure it's needed
 	AllowFiles bool `ini:"allowFiles"`
+
+	// Origins 可动态更新的origin白名单, 与AllowOrigins、AllowOriginFunc任一匹配即允许
+	// AllowCredentials为true时其中的*不生效, 否则任意站点都能携带cookie跨域访问
+	Origins *OriginList `ini:"-"`
 }
 
-func CorsMiddleware(config Config) gin.HandlerFunc {
+func (config Config) corsConfig() cors.Config {
 	corsConfig := cors.Config{
-		AllowOrigins:     config.AllowOrigins,
-		AllowMethods:     config.AllowMethods,
-		AllowHeaders:     config.AllowHeaders,
-		AllowCredentials: config.AllowCredentials,
-		MaxAge:           config.MaxAge,
+		AllowAllOrigins:        config.allowAllOrigins(),
+		AllowOrigins:           config.AllowOrigins,
+		AllowOriginFunc:        config.AllowOriginFunc,
+		AllowMethods:           config.AllowMethods,
+		AllowHeaders:           config.AllowHeaders,
+		AllowCredentials:       config.AllowCredentials,
+		ExposeHeaders:          config.ExposeHeaders,
+		MaxAge:                 config.MaxAge,
+		AllowWildcard:          config.AllowWildcard,
+		AllowBrowserExtensions: config.AllowBrowserExtensions,
+		AllowWebSockets:        config.AllowWebSockets,
+		AllowFiles:             config.AllowFiles,
+	}
+	if config.Origins != nil {
+		origins, fn := config.Origins, config.AllowOriginFunc
+		credentials := config.AllowCredentials
+		corsConfig.AllowOriginFunc = func(origin string) bool {
+			return origins.allow(origin, credentials) || (fn != nil && fn(origin))
+		}
+	}
+	return corsConfig
+}
+
+// allowAllOrigins gin-contrib/cors不允许AllowAllOrigins与origin列表同时配置, 同时配置时以列表为准
+func (config Config) allowAllOrigins() bool {
+	return config.AllowAllOrigins && len(config.AllowOrigins) == 0 && config.AllowOriginFunc == nil && config.Origins == nil
+}
+
+// AllowOrigin 按与CorsMiddleware相同的规则校验origin, 供websocket等非CORS场景复用
+// AllowCredentials为true时AllowAllOrigins和*不生效, 否则任意站点都能带着cookie建立websocket连接
+func (config Config) AllowOrigin(origin string) bool {
+	if config.allowAllOrigins() && !config.AllowCredentials {
+		return true
+	}
+	for _, o := range config.AllowOrigins {
+		if o == origin || (o == "*" && !config.AllowCredentials) {
+			return true
+		}
+		if config.AllowWildcard && matchOrigin(o, origin) {
+			return true
+		}
+	}
+	if config.Origins != nil && config.Origins.allow(origin, config.AllowCredentials) {
+		return true
+	}
+	return config.AllowOriginFunc != nil && config.AllowOriginFunc(origin)
+}
+
+func CorsMiddleware(config Config) gin.HandlerFunc {
+	return cors.New(config.corsConfig())
+}
+
+// CorsGroupsMiddleware 按路由前缀使用不同的CORS策略, key为RouterGroup.BasePath(), 最长前缀优先
+// 都不匹配时使用def, def为nil时不处理; 需注册在engine上, 未注册OPTIONS路由的预检请求才能被处理
+func CorsGroupsMiddleware(def *Config, groups map[string]Config) gin.HandlerFunc {
+	var defHandler gin.HandlerFunc
+	if def != nil {
+		defHandler = CorsMiddleware(*def)
+	}
+	prefixes := make([]string, 0, len(groups))
+	handlers := make(map[string]gin.HandlerFunc, len(groups))
+	for prefix, config := range groups {
+		prefix = "/" + strings.Trim(prefix, "/")
+		prefixes = append(prefixes, prefix)
+		handlers[prefix] = CorsMiddleware(config)
+	}
+	sort.Slice(prefixes, func(i, j int) bool { return len(prefixes[i]) > len(prefixes[j]) })
+
+	return func(ctx *gin.Context) {
+		path := ctx.Request.URL.Path
+		for _, prefix := range prefixes {
+			if prefix == "/" || path == prefix || strings.HasPrefix(path, prefix+"/") {
+				handlers[prefix](ctx)
+				return
+			}
+		}
+		if defHandler != nil {
+			defHandler(ctx)
+		}
+	}
+}
+
+// OriginList 可在运行中替换的origin白名单, 支持*和https://*.example.com形式的通配, *在携带凭证的CORS配置中不生效
+type OriginList struct {
+	origins atomic.Value
+}
+
+func NewOriginList(origins ...string) *OriginList {
+	l := &OriginList{}
+	l.Set(origins)
+	return l
+}
+
+func (l *OriginList) Set(origins []string) {
+	list := make([]string, 0, len(origins))
+	for _, o := range origins {
+		if o = strings.TrimSpace(o); o != "" {
+			list = append(list, strings.TrimSuffix(o, "/"))
+		}
+	}
+	l.origins.Store(list)
+}
+
+func (l *OriginList) Origins() []string {
+	list, _ := l.origins.Load().([]string)
+	return list
+}
+
+func (l *OriginList) Allow(origin string) bool {
+	return l.allow(origin, false)
+}
+
+// allow credentials为true时忽略*, 只匹配明确的origin和子域名通配
+func (l *OriginList) allow(origin string, credentials bool) bool {
+	for _, o := range l.Origins() {
+		if o == "*" {
+			if credentials {
+				continue
+			}
+			return true
+		}
+		if o == origin || matchOrigin(o, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadConf 从configx ini/yaml重新读取, 值以空格分隔, 配置文件重新加载后调用
+func (l *OriginList) LoadConf(sec, key string) *OriginList {
+	l.Set(configx.GetConfs(sec, key))
+	return l
+}
+
+// WatchKratos 从configx.InitKratos加载的配置读取并监听变更, 值可以是数组或以空格、逗号分隔的字符串
+func (l *OriginList) WatchKratos(key string) error {
+	c := configx.GetKratos()
+	if c == nil {
+		return errors.New("kratos config not init")
+	}
+	if origins, err := kratosStrings(c.Value(key)); err == nil {
+		l.Set(origins)
+	}
+	return c.Watch(key, func(_ string, v config.Value) {
+		origins, err := kratosStrings(v)
+		if err != nil {
+			logx.E("http.middleware.cors", "reload origins failed key:%s, err:%+v", key, err)
+			return
+		}
+		l.Set(origins)
+	})
+}
+
+// kratosStrings 读取字符串列表配置, 值可以是数组或以空格、逗号分隔的字符串
+func kratosStrings(v config.Value) ([]string, error) {
+	if values, err := v.Slice(); err == nil {
+		list := make([]string, 0, len(values))
+		for _, item := range values {
+			if s, err := item.String(); err == nil {
+				list = append(list, s)
+			}
+		}
+		return list, nil
+	}
+	s, err := v.String()
+	if err != nil {
+		return nil, err
+	}
+	return strings.FieldsFunc(s, func(r rune) bool { return r == ' ' || r == ',' }), nil
+}
+
+// matchOrigin pattern中只允许一个*
+func matchOrigin(pattern, origin string) bool {
+	i := strings.Index(pattern, "*")
+	if i < 0 || strings.Count(pattern, "*") > 1 {
+		return false
 	}
-	return cors.New(corsConfig)
+	prefix, suffix := pattern[:i], pattern[i+1:]
+	return len(origin) >= len(prefix)+len(suffix) &&
+		strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix)
 }
 
 func NoCacheMiddleware() gin.HandlerFunc {
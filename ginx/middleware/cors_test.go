@@ -0,0 +1,120 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCorsGroupsMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	admin := NewOriginList("https://admin.example.com")
+	r := gin.New()
+	r.Use(CorsGroupsMiddleware(
+		&Config{AllowAllOrigins: true, AllowMethods: []string{"GET"}},
+		map[string]Config{
+			"/admin": {
+				Origins:          admin,
+				AllowMethods:     []string{"GET", "POST"},
+				AllowCredentials: true,
+				ExposeHeaders:    []string{"X-Total"},
+			},
+		},
+	))
+	r.GET("/admin/users", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+	r.GET("/public", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+
+	do := func(method, target, origin string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		req.Header.Set("Origin", origin)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do(http.MethodGet, "/admin/users", "https://admin.example.com")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "https://admin.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "X-Total", w.Header().Get("Access-Control-Expose-Headers"))
+
+	w = do(http.MethodOptions, "/admin/users", "https://admin.example.com")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "true", w.Header().Get("Access-Control-Allow-Credentials"))
+
+	assert.Equal(t, http.StatusForbidden, do(http.MethodGet, "/admin/users", "https://evil.example.com").Code)
+	admin.Set([]string{"https://*.example.com"})
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/admin/users", "https://evil.example.com").Code)
+
+	w = do(http.MethodGet, "/public", "https://any.example.org")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestCorsOriginsCredentials(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	origins := NewOriginList("*", "https://admin.example.com")
+	r := gin.New()
+	r.Use(CorsMiddleware(Config{
+		Origins:          origins,
+		AllowMethods:     []string{"GET"},
+		AllowCredentials: true,
+	}))
+	r.GET("/users", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+
+	do := func(origin string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/users", nil)
+		req.Header.Set("Origin", origin)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// 携带凭证时*不生效, 不会反射任意origin
+	w := do("https://evil.example.org")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Credentials"))
+
+	w = do("https://admin.example.com")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "https://admin.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", w.Header().Get("Access-Control-Allow-Credentials"))
+
+	assert.False(t, Config{Origins: origins, AllowCredentials: true}.AllowOrigin("https://evil.example.org"))
+	assert.True(t, Config{Origins: origins}.AllowOrigin("https://evil.example.org"))
+	assert.True(t, origins.Allow("https://evil.example.org"))
+
+	evil := "https://evil.example.org"
+	assert.False(t, Config{AllowOrigins: []string{"*"}, AllowCredentials: true}.AllowOrigin(evil))
+	assert.True(t, Config{AllowOrigins: []string{"*"}}.AllowOrigin(evil))
+	assert.False(t, Config{AllowAllOrigins: true, AllowCredentials: true}.AllowOrigin(evil))
+	assert.True(t, Config{AllowAllOrigins: true}.AllowOrigin(evil))
+}
+
+func TestCorsAllowAllOriginsConflict(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	// 同时配置AllowAllOrigins和origin列表时以列表为准, 不在启动时panic
+	config := Config{
+		AllowAllOrigins: true,
+		AllowOrigins:    []string{"https://app.example.com"},
+		AllowMethods:    []string{"GET"},
+	}
+	var handler gin.HandlerFunc
+	assert.NotPanics(t, func() { handler = CorsMiddleware(config) })
+	assert.NotPanics(t, func() { CorsMiddleware(Config{AllowAllOrigins: true, Origins: NewOriginList("https://app.example.com")}) })
+
+	r := gin.New()
+	r.Use(handler)
+	r.GET("/users", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+	for origin, code := range map[string]int{"https://app.example.com": http.StatusOK, "https://evil.example.org": http.StatusForbidden} {
+		req := httptest.NewRequest(http.MethodGet, "/users", nil)
+		req.Header.Set("Origin", origin)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, code, w.Code, origin)
+		assert.Equal(t, code == http.StatusOK, config.AllowOrigin(origin), origin)
+	}
+}
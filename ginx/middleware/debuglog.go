@@ -0,0 +1,204 @@
+package middleware
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-kratos/kratos/v2/config"
+	"github.com/ytf606/golibs/configx"
+	"github.com/ytf606/golibs/logx"
+	"github.com/ytf606/golibs/logx/logutils"
+)
+
+// DebugLogHeader 单个请求开启调试日志的请求头
+const DebugLogHeader = "X-Debug-Log"
+
+// DefaultDebugLogMaxTTL 调试头默认的最长有效期
+const DefaultDebugLogMaxTTL = time.Hour
+
+// DebugLogConfig LoggerMiddleware按请求开启调试日志, 只对当前请求生效
+// 作用于logx、request包的http/rpcx日志以及db.WithContext的sql输出
+type DebugLogConfig struct {
+	// Secret 调试头的签名密钥, 为空时不接受调试头
+	Secret string
+	// Header 调试头, 默认X-Debug-Log, 值为"级别:过期时间戳:签名", 由SignDebugLog生成
+	Header string
+	// Uids 开启调试日志的uid白名单
+	// LoggerMiddleware通常注册在鉴权之前, 此时gin.Context中还没有uid, 按登录uid开启请在鉴权后注册DebugLogUidMiddleware
+	Uids *UidList
+	// UidHeader 网关透传uid的请求头, gin.Context中已有uid时优先使用
+	// 客户端可以伪造该头, 只能在由可信网关覆盖写入该头的部署中使用
+	UidHeader string
+	// Level 命中uid白名单时的级别, 默认DEBUG
+	Level string
+	// MaxTTL 调试头的最长有效期, 过期时间晚于当前时间加MaxTTL的调试头不接受, 默认DefaultDebugLogMaxTTL
+	MaxTTL time.Duration
+}
+
+// SignDebugLog 生成调试头的值, expires之后失效
+func SignDebugLog(secret, level string, expires time.Time) string {
+	payload := strings.ToUpper(level) + ":" + strconv.FormatInt(expires.Unix(), 10)
+	return payload + ":" + debugLogSign(secret, payload)
+}
+
+func debugLogSign(secret, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (config DebugLogConfig) withDefault() DebugLogConfig {
+	if config.Header == "" {
+		config.Header = DebugLogHeader
+	}
+	if config.Level == "" {
+		config.Level = "DEBUG"
+	}
+	config.Level = strings.ToUpper(config.Level)
+	if config.MaxTTL <= 0 {
+		config.MaxTTL = DefaultDebugLogMaxTTL
+	}
+	return config
+}
+
+// debugLevel 返回请求开启的日志级别及开启方式, 未开启时返回空
+func (config DebugLogConfig) debugLevel(c *gin.Context) (string, string) {
+	if value := c.GetHeader(config.Header); value != "" && config.Secret != "" {
+		lvl, err := config.verify(value, time.Now())
+		if err == nil {
+			return lvl, "header"
+		}
+		// 任何客户端都能带上调试头, 按DEBUG输出避免刷屏
+		logx.Dx(c, "[ginx_debuglog]", "invalid debug log header err:%v, client_ip:%s", err, c.ClientIP())
+	}
+	return config.uidLevel(c, config.UidHeader)
+}
+
+// uidLevel 按gin.Context中的uid或uidHeader匹配白名单
+func (config DebugLogConfig) uidLevel(c *gin.Context, uidHeader string) (string, string) {
+	if config.Uids == nil {
+		return "", ""
+	}
+	uid := ""
+	if val, ok := c.Get("uid"); ok {
+		uid = fmt.Sprintf("%v", val)
+	} else if uidHeader != "" {
+		uid = c.GetHeader(uidHeader)
+	}
+	if uid != "" && config.Uids.Allow(uid) {
+		return config.Level, "uid:" + uid
+	}
+	return "", ""
+}
+
+// DebugLogUidMiddleware 按鉴权后gin.Context中的uid匹配config.Uids开启调试日志, 需注册在鉴权中间件之后
+// 只使用config.Uids和config.Level, 不读取UidHeader, 之后的logx、request和db.WithContext输出按该级别
+func DebugLogUidMiddleware(config DebugLogConfig) gin.HandlerFunc {
+	config = config.withDefault()
+	return func(c *gin.Context) {
+		// 调试头已开启的请求不覆盖
+		if c.GetString(logutils.CtxLevelKey) == "" {
+			if lvl, by := config.uidLevel(c, ""); lvl != "" {
+				c.Set(logutils.CtxLevelKey, lvl)
+				logx.Ix(c, "[ginx_debuglog]", "request log level set to %s by %s", lvl, by)
+			}
+		}
+		c.Next()
+	}
+}
+
+// verify 校验"级别:过期时间戳:签名", 泄露的签名最多在MaxTTL内有效
+func (config DebugLogConfig) verify(value string, now time.Time) (string, error) {
+	parts := strings.Split(value, ":")
+	if len(parts) != 3 {
+		return "", errors.New("malformed debug log header")
+	}
+	lvl := strings.ToUpper(parts[0])
+	if _, ok := logutils.LevelMap[lvl]; !ok {
+		return "", fmt.Errorf("unknown log level %s", parts[0])
+	}
+	expires, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("invalid expires %s", parts[1])
+	}
+	sign := debugLogSign(config.Secret, lvl+":"+parts[1])
+	if !hmac.Equal([]byte(sign), []byte(strings.ToLower(parts[2]))) {
+		return "", errors.New("debug log header sign mismatch")
+	}
+	if now.Unix() > expires {
+		return "", errors.New("debug log header expired")
+	}
+	if config.MaxTTL > 0 && time.Unix(expires, 0).Sub(now) > config.MaxTTL {
+		return "", fmt.Errorf("debug log header expires beyond max ttl %s", config.MaxTTL)
+	}
+	return lvl, nil
+}
+
+// UidList 可在运行中替换的uid白名单
+type UidList struct {
+	uids atomic.Value
+}
+
+func NewUidList(uids ...string) *UidList {
+	l := &UidList{}
+	l.Set(uids)
+	return l
+}
+
+func (l *UidList) Set(uids []string) {
+	set := make(map[string]struct{}, len(uids))
+	for _, uid := range uids {
+		if uid = strings.TrimSpace(uid); uid != "" {
+			set[uid] = struct{}{}
+		}
+	}
+	l.uids.Store(set)
+}
+
+func (l *UidList) Uids() []string {
+	set, _ := l.uids.Load().(map[string]struct{})
+	uids := make([]string, 0, len(set))
+	for uid := range set {
+		uids = append(uids, uid)
+	}
+	return uids
+}
+
+func (l *UidList) Allow(uid string) bool {
+	set, _ := l.uids.Load().(map[string]struct{})
+	_, ok := set[uid]
+	return ok
+}
+
+// LoadConf 从configx ini/yaml重新读取, 值以空格分隔, 配置文件重新加载后调用
+func (l *UidList) LoadConf(sec, key string) *UidList {
+	l.Set(configx.GetConfs(sec, key))
+	return l
+}
+
+// WatchKratos 从configx.InitKratos加载的配置读取并监听变更, 值可以是数组或以空格、逗号分隔的字符串
+func (l *UidList) WatchKratos(key string) error {
+	c := configx.GetKratos()
+	if c == nil {
+		return errors.New("kratos config not init")
+	}
+	if uids, err := kratosStrings(c.Value(key)); err == nil {
+		l.Set(uids)
+	}
+	return c.Watch(key, func(_ string, v config.Value) {
+		uids, err := kratosStrings(v)
+		if err != nil {
+			logx.E("[ginx_debuglog]", "reload uids failed key:%s, err:%+v", key, err)
+			return
+		}
+		l.Set(uids)
+	})
+}
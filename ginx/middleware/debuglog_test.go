@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/ytf606/golibs/logx"
+	"github.com/ytf606/golibs/logx/log4go"
+	"github.com/ytf606/golibs/logx/logutils"
+)
+
+func TestDebugLog(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(LoggerMiddleware(DebugLogConfig{
+		Secret:    "secret",
+		Uids:      NewUidList("100", " 200 "),
+		UidHeader: "X-Uid",
+	}))
+	r.GET("/level", func(c *gin.Context) { c.String(http.StatusOK, c.GetString(logutils.CtxLevelKey)) })
+
+	do := func(headers map[string]string) string {
+		req := httptest.NewRequest(http.MethodGet, "/level", nil)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Body.String()
+	}
+
+	assert.Equal(t, "", do(nil))
+	assert.Equal(t, "FINE", do(map[string]string{DebugLogHeader: SignDebugLog("secret", "fine", time.Now().Add(time.Minute))}))
+	assert.Equal(t, "", do(map[string]string{DebugLogHeader: SignDebugLog("other", "DEBUG", time.Now().Add(time.Minute))}))
+	assert.Equal(t, "", do(map[string]string{DebugLogHeader: SignDebugLog("secret", "DEBUG", time.Now().Add(-time.Minute))}))
+	assert.Equal(t, "", do(map[string]string{DebugLogHeader: "DEBUG:1:abc"}))
+	// 过期时间超过MaxTTL的签名不接受
+	assert.Equal(t, "", do(map[string]string{DebugLogHeader: SignDebugLog("secret", "DEBUG", time.Now().Add(2*time.Hour))}))
+	assert.Equal(t, "DEBUG", do(map[string]string{DebugLogHeader: SignDebugLog("secret", "DEBUG", time.Now().Add(59*time.Minute))}))
+	assert.Equal(t, "DEBUG", do(map[string]string{"X-Uid": "200"}))
+	assert.Equal(t, "", do(map[string]string{"X-Uid": "300"}))
+
+	config := DebugLogConfig{Secret: "secret", MaxTTL: 24 * time.Hour}.withDefault()
+	now := time.Now()
+	_, err := config.verify(SignDebugLog("secret", "DEBUG", now.Add(2*time.Hour)), now)
+	assert.NoError(t, err)
+	_, err = config.verify(SignDebugLog("secret", "DEBUG", now.Add(25*time.Hour)), now)
+	assert.Error(t, err)
+}
+
+func TestDebugLogEscalate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := &lineWriter{}
+	log4go.Global = log4go.Logger{"test": &log4go.Filter{Level: log4go.INFO, LogWriter: w}}
+	logutils.Inited, logutils.Level = true, "INFO"
+	logutils.SetLevel("INFO")
+	defer func() {
+		logutils.Inited, logutils.Level = false, "ERROR"
+		logutils.SetLevel("ERROR")
+		log4go.Global = log4go.NewDefaultLogger(log4go.DEBUG)
+	}()
+
+	config := DebugLogConfig{Secret: "secret", Uids: NewUidList("100")}
+	r := gin.New()
+	r.Use(LoggerMiddleware(config), func(c *gin.Context) {
+		// 模拟鉴权中间件
+		if uid := c.GetHeader("X-Auth-Uid"); uid != "" {
+			c.Set("uid", uid)
+		}
+	}, DebugLogUidMiddleware(config))
+	r.GET("/", func(c *gin.Context) {
+		logx.Dx(c, "[debug_test]", "uid:%s", c.GetString("uid"))
+		// db.WithContext按该条件开启sql输出
+		lvl, ok := logutils.CtxLevel(c)
+		c.String(http.StatusOK, "%v", ok && lvl <= log4go.DEBUG)
+	})
+	do := func(headers map[string]string) (string, string) {
+		w.lines = nil
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec.Body.String(), w.find("[debug_test]")
+	}
+
+	body, line := do(nil)
+	assert.Equal(t, "false", body)
+	assert.Empty(t, line)
+
+	body, line = do(map[string]string{"X-Auth-Uid": "100"})
+	assert.Equal(t, "true", body)
+	assert.Contains(t, line, "uid:100")
+
+	body, line = do(map[string]string{"X-Auth-Uid": "200"})
+	assert.Equal(t, "false", body)
+	assert.Empty(t, line)
+
+	body, line = do(map[string]string{DebugLogHeader: SignDebugLog("secret", "DEBUG", time.Now().Add(time.Minute))})
+	assert.Equal(t, "true", body)
+	assert.NotEmpty(t, line)
+}
@@ -0,0 +1,292 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ytf606/golibs/errorx"
+	"github.com/ytf606/golibs/ginx"
+	"github.com/ytf606/golibs/logx"
+	"github.com/ytf606/golibs/service/redis"
+)
+
+const (
+	HeaderIdempotencyKey      = "Idempotency-Key"
+	HeaderIdempotencyReplayed = "Idempotent-Replayed"
+)
+
+type IdempotencyConfig struct {
+	// RedisName redis.DefaultManager中的实例名, Store为空时使用, 需在redis初始化之后创建中间件
+	RedisName string
+	// Store 自定义记录存储, 如NewMemoryIdempotencyStore
+	Store IdempotencyStore
+	// Routers 需要幂等处理的路由, 为空时对所有POST/PUT/PATCH/DELETE生效, 以*结尾的按前缀匹配
+	Routers []string
+	// Required 为true时缺少Idempotency-Key返回400, 否则直接放行
+	Required bool
+	// TTL 响应缓存时间, 默认24h
+	TTL time.Duration
+	// LockTTL 处理中状态的最长保留时间, 默认30s
+	LockTTL time.Duration
+	// Prefix redis key前缀
+	Prefix string
+	// Scope 区分key的归属, 如按uid隔离, 为空时全局共享
+	Scope func(c *gin.Context) string
+}
+
+type idempotencyRecord struct {
+	Done     bool                `json:"done"`
+	BodyHash string              `json:"body_hash"`
+	Status   int                 `json:"status,omitempty"`
+	Header   map[string][]string `json:"header,omitempty"`
+	Body     []byte              `json:"body,omitempty"`
+}
+
+// IdempotencyMiddleware 对带Idempotency-Key的请求只执行一次, 重复请求直接返回首次响应
+// 首次请求处理中时重复请求返回409, 同一key请求体不一致返回422, 5xx响应不缓存以便客户端重试
+// Store和RedisName都未配置或RedisName不存在时在创建时panic
+func IdempotencyMiddleware(config IdempotencyConfig) gin.HandlerFunc {
+	logTag := "http.middleware.idempotency"
+	store := config.Store
+	if store == nil {
+		if config.RedisName == "" {
+			panic("idempotency middleware: Store or RedisName is required")
+		}
+		store = NewRedisIdempotencyStore(redis.NewRedisManager().Get(config.RedisName))
+	}
+	if config.TTL <= 0 {
+		config.TTL = 24 * time.Hour
+	}
+	if config.LockTTL <= 0 {
+		config.LockTTL = 30 * time.Second
+	}
+	if config.Prefix == "" {
+		config.Prefix = "golibs:idempotency:"
+	}
+	return func(c *gin.Context) {
+		if !idempotencyMatch(config, c) {
+			c.Next()
+			return
+		}
+		key := c.GetHeader(HeaderIdempotencyKey)
+		if key == "" {
+			if config.Required {
+				ginx.ErrResponse(c, errorx.ErrIdempotencyKeyMissing)
+				c.Abort()
+				return
+			}
+			c.Next()
+			return
+		}
+
+		var body []byte
+		if c.Request.Body != nil {
+			body, _ = ioutil.ReadAll(c.Request.Body)
+		}
+		c.Request.Body = ioutil.NopCloser(bytes.NewBuffer(body))
+		sum := sha256.Sum256(body)
+		bodyHash := hex.EncodeToString(sum[:])
+
+		scope := ""
+		if config.Scope != nil {
+			scope = config.Scope(c)
+		}
+		redisKey := fmt.Sprintf("%s%s:%s:%s:%s", config.Prefix, scope, c.Request.Method, c.FullPath(), key)
+
+		pending, _ := json.Marshal(&idempotencyRecord{BodyHash: bodyHash})
+		ok, err := store.SetNX(c, redisKey, pending, config.LockTTL)
+		if err != nil {
+			logx.Ex(c, logTag, "idempotency lock failed err:%+v, key:%s", err, redisKey)
+			ginx.ErrResponse(c, errorx.Wrap500Response(err, errorx.GinxIdempotencyStoreErr, ""))
+			c.Abort()
+			return
+		}
+		if !ok {
+			idempotencyReplay(c, store, redisKey, bodyHash)
+			return
+		}
+
+		// 5xx或handler panic时释放key, 以便客户端重试
+		saved := false
+		defer func() {
+			if saved {
+				return
+			}
+			if err := store.Del(c, redisKey); err != nil {
+				logx.Ex(c, logTag, "idempotency unlock failed err:%+v, key:%s", err, redisKey)
+			}
+		}()
+
+		blw := &bodyLogWriter{body: bytes.NewBuffer([]byte{}), ResponseWriter: c.Writer}
+		c.Writer = blw
+		c.Next()
+
+		status := c.Writer.Status()
+		if status >= http.StatusInternalServerError {
+			return
+		}
+		// 记录的是压缩前的body, 编码相关header由回放时的CompressMiddleware重新生成
+		header := c.Writer.Header().Clone()
+		header.Del("Content-Encoding")
+		header.Del("Content-Length")
+		done, _ := json.Marshal(&idempotencyRecord{
+			Done:     true,
+			BodyHash: bodyHash,
+			Status:   status,
+			Header:   header,
+			Body:     blw.body.Bytes(),
+		})
+		if err := store.Set(c, redisKey, done, config.TTL); err != nil {
+			logx.Ex(c, logTag, "idempotency save failed err:%+v, key:%s", err, redisKey)
+			return
+		}
+		saved = true
+	}
+}
+
+func idempotencyMatch(config IdempotencyConfig, c *gin.Context) bool {
+	if len(config.Routers) > 0 {
+		return matchRouter(config.Routers, c.FullPath(), c.Request.URL.Path)
+	}
+	switch c.Request.Method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+func idempotencyReplay(c *gin.Context, store IdempotencyStore, redisKey, bodyHash string) {
+	logTag := "http.middleware.idempotency"
+	raw, err := store.Get(c, redisKey)
+	if err == redis.Nil {
+		// 首次请求在两次读取之间失败并释放了key
+		ginx.ErrResponse(c, errorx.ErrIdempotencyConflict)
+		c.Abort()
+		return
+	}
+	record := new(idempotencyRecord)
+	if err == nil {
+		err = json.Unmarshal(raw, record)
+	}
+	if err != nil {
+		logx.Ex(c, logTag, "idempotency load failed err:%+v, key:%s", err, redisKey)
+		ginx.ErrResponse(c, errorx.Wrap500Response(err, errorx.GinxIdempotencyStoreErr, ""))
+		c.Abort()
+		return
+	}
+
+	if record.BodyHash != bodyHash {
+		ginx.ErrResponse(c, errorx.ErrIdempotencyMismatch)
+		c.Abort()
+		return
+	}
+	if !record.Done {
+		ginx.ErrResponse(c, errorx.ErrIdempotencyConflict)
+		c.Abort()
+		return
+	}
+
+	header := c.Writer.Header()
+	for k, values := range record.Header {
+		header[k] = values
+	}
+	header.Set(HeaderIdempotencyReplayed, "true")
+	c.Data(record.Status, header.Get("Content-Type"), record.Body)
+	c.Abort()
+}
+
+// IdempotencyStore 保存幂等记录, Get在key不存在时返回redis.Nil
+type IdempotencyStore interface {
+	// SetNX key不存在时写入并返回true
+	SetNX(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error)
+	Get(ctx context.Context, key string) ([]byte, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Del(ctx context.Context, key string) error
+}
+
+type redisIdempotencyStore struct {
+	client *redis.Ins
+}
+
+// NewRedisIdempotencyStore client可通过redis.DefaultManager.Get(name)获取
+func NewRedisIdempotencyStore(client *redis.Ins) IdempotencyStore {
+	return &redisIdempotencyStore{client: client}
+}
+
+func (s *redisIdempotencyStore) SetNX(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	return s.client.SetNX(ctx, key, value, ttl).Result()
+}
+
+func (s *redisIdempotencyStore) Get(ctx context.Context, key string) ([]byte, error) {
+	return s.client.Get(ctx, key).Bytes()
+}
+
+func (s *redisIdempotencyStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return s.client.Set(ctx, key, value, ttl).Err()
+}
+
+func (s *redisIdempotencyStore) Del(ctx context.Context, key string) error {
+	return s.client.Del(ctx, key).Err()
+}
+
+type memoryIdempotencyItem struct {
+	value  []byte
+	expire time.Time
+}
+
+type memoryIdempotencyStore struct {
+	mu    sync.Mutex
+	items map[string]memoryIdempotencyItem
+}
+
+// NewMemoryIdempotencyStore 单机内存实现, 过期记录在访问时清理, 多实例部署时请使用redis
+func NewMemoryIdempotencyStore() IdempotencyStore {
+	return &memoryIdempotencyStore{items: make(map[string]memoryIdempotencyItem)}
+}
+
+func (s *memoryIdempotencyStore) SetNX(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if item, ok := s.items[key]; ok && time.Now().Before(item.expire) {
+		return false, nil
+	}
+	s.items[key] = memoryIdempotencyItem{value: value, expire: time.Now().Add(ttl)}
+	return true, nil
+}
+
+func (s *memoryIdempotencyStore) Get(ctx context.Context, key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	item, ok := s.items[key]
+	if !ok {
+		return nil, redis.Nil
+	}
+	if time.Now().After(item.expire) {
+		delete(s.items, key)
+		return nil, redis.Nil
+	}
+	return item.value, nil
+}
+
+func (s *memoryIdempotencyStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.items[key] = memoryIdempotencyItem{value: value, expire: time.Now().Add(ttl)}
+	return nil
+}
+
+func (s *memoryIdempotencyStore) Del(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.items, key)
+	return nil
+}
@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var calls int32
+	started, release := make(chan struct{}), make(chan struct{})
+	r := gin.New()
+	r.Use(gin.Recovery(), IdempotencyMiddleware(IdempotencyConfig{
+		Store:    NewMemoryIdempotencyStore(),
+		Required: true,
+	}))
+	r.POST("/order", func(c *gin.Context) {
+		n := atomic.AddInt32(&calls, 1)
+		c.Header("X-Call", strings.Repeat("x", int(n)))
+		c.String(http.StatusCreated, "created")
+	})
+	r.POST("/slow", func(c *gin.Context) {
+		close(started)
+		<-release
+		c.String(http.StatusOK, "ok")
+	})
+	var panics int32
+	r.POST("/panic", func(c *gin.Context) {
+		if atomic.AddInt32(&panics, 1) == 1 {
+			panic("boom")
+		}
+		c.String(http.StatusOK, "recovered")
+	})
+
+	do := func(path, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		if key != "" {
+			req.Header.Set(HeaderIdempotencyKey, key)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do("/order", "", `{"a":1}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = do("/order", "k1", `{"a":1}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "created", w.Body.String())
+	assert.Empty(t, w.Header().Get(HeaderIdempotencyReplayed))
+
+	// 回放首次响应, handler不再执行
+	w = do("/order", "k1", `{"a":1}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "created", w.Body.String())
+	assert.Equal(t, "x", w.Header().Get("X-Call"))
+	assert.Equal(t, "true", w.Header().Get(HeaderIdempotencyReplayed))
+	assert.EqualValues(t, 1, atomic.LoadInt32(&calls))
+
+	w = do("/order", "k1", `{"a":2}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	// 首次请求处理中
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- do("/slow", "k2", "") }()
+	<-started
+	assert.Equal(t, http.StatusConflict, do("/slow", "k2", "").Code)
+	close(release)
+	assert.Equal(t, http.StatusOK, (<-done).Code)
+
+	// handler panic后释放key, 重试可以再次执行
+	w = do("/panic", "k3", "")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	w = do("/panic", "k3", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "recovered", w.Body.String())
+}
+
+func TestIdempotencyMiddlewareConfig(t *testing.T) {
+	// 配置错误在创建时暴露, 而不是每个请求500
+	assert.Panics(t, func() { IdempotencyMiddleware(IdempotencyConfig{}) })
+	assert.Panics(t, func() { IdempotencyMiddleware(IdempotencyConfig{RedisName: "missing"}) })
+	assert.NotPanics(t, func() { IdempotencyMiddleware(IdempotencyConfig{Store: NewMemoryIdempotencyStore()}) })
+}
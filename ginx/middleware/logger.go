@@ -2,15 +2,20 @@ package middleware
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"net/http"
 	"os"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/ytf606/golibs/ginx"
 	"github.com/ytf606/golibs/logx"
 	"github.com/ytf606/golibs/logx/logtrace"
+	"github.com/ytf606/golibs/logx/logutils"
 	"github.com/gin-gonic/gin"
 )
 
@@ -18,8 +23,43 @@ var (
 	hostname, _ = os.Hostname()
 )
 
-// 创建logger相关
-func LoggerMiddleware() gin.HandlerFunc {
+// DefaultTraceBodyBytes LoggerMiddleware写入trace元数据的请求体最多字节数
+const DefaultTraceBodyBytes = 2048
+
+// TraceBodyConfig LoggerMiddleware写入trace元数据的请求体, 请求体会随该请求的每条日志输出
+type TraceBodyConfig struct {
+	// MaxBytes 最多记录的字节数, 0表示不限制
+	MaxBytes int
+	// ContentTypes 允许记录body的Content-Type前缀, 为空时全部记录
+	ContentTypes []string
+	// RedactKeys 需要脱敏的JSON字段, 支持user.mobile形式的路径, 不含.时匹配任意层级
+	RedactKeys []string
+}
+
+// DefaultTraceBodyConfig 与DefaultLoggerConfig相同的Content-Type和脱敏字段, 截断到DefaultTraceBodyBytes
+func DefaultTraceBodyConfig() TraceBodyConfig {
+	config := DefaultLoggerConfig()
+	return TraceBodyConfig{
+		MaxBytes:     DefaultTraceBodyBytes,
+		ContentTypes: config.ContentTypes,
+		RedactKeys:   config.RedactKeys,
+	}
+}
+
+// LoggerMiddleware 创建logger相关, 请求体按DefaultTraceBodyConfig脱敏和截断
+// 传入debug时按调试头或uid白名单为单个请求开启调试日志
+func LoggerMiddleware(debug ...DebugLogConfig) gin.HandlerFunc {
+	return LoggerMiddlewareWithConfig(DefaultTraceBodyConfig(), debug...)
+}
+
+// LoggerMiddlewareWithConfig 同LoggerMiddleware, 按config截断和脱敏写入trace元数据的请求体
+func LoggerMiddlewareWithConfig(config TraceBodyConfig, debug ...DebugLogConfig) gin.HandlerFunc {
+	redactor := newRedactor(config.RedactKeys)
+	var debugConfig *DebugLogConfig
+	if len(debug) > 0 {
+		dc := debug[0].withDefault()
+		debugConfig = &dc
+	}
 	return func(ctx *gin.Context) {
 		ctx.Set("logid", strconv.FormatInt(logx.Id(), 10))
 		ctx.Set("hostname", hostname)
@@ -27,23 +67,26 @@ func LoggerMiddleware() gin.HandlerFunc {
 		path := ctx.Request.URL.Path
 		raw := ctx.Request.URL.RawQuery
 		var body []byte
-		if ctx.Request.Body != nil {
-			body, _ = ioutil.ReadAll(ctx.Request.Body)
+		// 上传等流式body不读入内存, 由handler直接消费
+		if ctx.Request.Body != nil && !ginx.IsStreamBody(ctx.Request) && allowContentType(config.ContentTypes, ctx.ContentType()) {
+			body = redactor.redact(peekBody(ctx.Request, config.MaxBytes))
 		}
-		ctx.Request.Body = ioutil.NopCloser(bytes.NewBuffer(body))
 		if raw != "" {
 			path = path + "?" + raw
 		}
 
 		logtraceMap := logtrace.GenLogTraceMetadata()
 		logtraceMap.Set("request_uri", fmt.Sprintf("\"%s\"", path))
-		if len(body) > 0 && bytes.HasPrefix(body, []byte("{")) {
-			logtraceMap.Set("x_request_param", fmt.Sprintf("%s", body))
+		// 截断后不再是合法json的按字符串记录
+		if len(body) > 0 && bytes.HasPrefix(body, []byte("{")) && json.Valid(body) {
+			logtraceMap.Set("x_request_param", string(body))
 		} else {
-			logtraceMap.Set("request_param", fmt.Sprintf("\"%s\"", body))
+			logtraceMap.Set("request_param", strconv.Quote(string(body)))
 		}
 		logtraceMap.Set("request_method", fmt.Sprintf("\"%s\"", ctx.Request.Method))
 		logtraceMap.Set("request_client_ip", fmt.Sprintf("\"%s\"", ctx.ClientIP()))
+		// W3C Trace Context或B3, 原有traceid/rpcid头优先
+		logtrace.ExtractHeaders(logtraceMap, ctx.GetHeader)
 		if traceId := ctx.GetHeader("traceid"); traceId != "" {
 			logtraceMap.Set("x_trace_id", "\""+traceId+"\"")
 			if strings.HasPrefix(traceId, "pts_") {
@@ -62,14 +105,83 @@ func LoggerMiddleware() gin.HandlerFunc {
 			logtraceMap.Set("x_rpcid", "\""+rpcId+"\"")
 		}
 		ctx.Set(logtrace.GetMetadataKey(), logtraceMap)
+		if debugConfig != nil {
+			if lvl, by := debugConfig.debugLevel(ctx); lvl != "" {
+				ctx.Set(logutils.CtxLevelKey, lvl)
+				logx.Ix(ctx, "[ginx_debuglog]", "request log level set to %s by %s", lvl, by)
+			}
+		}
+
+		name := ctx.FullPath()
+		if name == "" {
+			name = ctx.Request.URL.Path
+		}
+		span := logtrace.StartSpanWith(ctx, ctx.Request.Method+" "+name, logtrace.SpanKindServer)
+		span.SetAttribute("http.method", ctx.Request.Method).
+			SetAttribute("http.target", path).
+			SetAttribute("http.client_ip", ctx.ClientIP())
+		ctx.Set(logtrace.GetSpanKey(), span)
+		ctx.Header(logtrace.HeaderTraceparent, logtrace.InjectHeaders(ctx)[logtrace.HeaderTraceparent])
+
+		ctx.Next()
+
+		status := ctx.Writer.Status()
+		span.SetAttribute("http.status_code", status)
+		if status >= 500 && span.StatusCode == logtrace.StatusUnset {
+			span.SetStatus(logtrace.StatusError, http.StatusText(status))
+		}
+		span.End()
+	}
+}
+
+// LoggerConfig 请求日志配置
+type LoggerConfig struct {
+	// MaxBodyBytes 请求体、响应体最多记录的字节数, 0表示不限制
+	MaxBodyBytes int
+	// ContentTypes 允许记录body的Content-Type前缀, 为空时全部记录
+	ContentTypes []string
+	// RedactKeys 需要脱敏的JSON字段, 支持user.mobile形式的路径, 不含.时匹配任意层级
+	RedactKeys []string
+	// LogHeaders 需要记录的请求头
+	LogHeaders []string
+	// RedactHeaders 需要脱敏的请求头
+	RedactHeaders []string
+	// SkipPaths 不记录日志的路由, 以*结尾的按前缀匹配
+	SkipPaths []string
+	// Skip 自定义跳过规则
+	Skip func(c *gin.Context) bool
+}
+
+// DefaultLoggerConfig 只记录文本类body, 对常见敏感字段脱敏
+func DefaultLoggerConfig() LoggerConfig {
+	return LoggerConfig{
+		ContentTypes: []string{
+			"application/json",
+			"application/x-www-form-urlencoded",
+			"application/xml",
+			"text/",
+		},
+		RedactKeys:    []string{"password", "token", "mobile"},
+		RedactHeaders: []string{"Authorization", "Cookie", "Token"},
 	}
 }
 
 // Logger -
 func Logger() gin.HandlerFunc {
+	return LoggerWithConfig(DefaultLoggerConfig())
+}
+
+// LoggerWithConfig -
+func LoggerWithConfig(config LoggerConfig) gin.HandlerFunc {
+	redactor := newRedactor(config.RedactKeys)
 	return func(c *gin.Context) {
+		if len(config.SkipPaths) > 0 && matchRouter(config.SkipPaths, c.FullPath(), c.Request.URL.Path) {
+			c.Next()
+			return
+		}
+
 		// Response
-		blw := &bodyLogWriter{body: bytes.NewBuffer([]byte{}), ResponseWriter: c.Writer}
+		blw := &bodyLogWriter{body: bytes.NewBuffer([]byte{}), ResponseWriter: c.Writer, limit: config.MaxBodyBytes}
 		c.Writer = blw
 
 		// Start timer
@@ -78,16 +190,15 @@ func Logger() gin.HandlerFunc {
 		raw := c.Request.URL.RawQuery
 
 		var body []byte
-		if c.Request.Body != nil {
-			body, _ = ioutil.ReadAll(c.Request.Body)
+		if c.Request.Body != nil && !ginx.IsStreamBody(c.Request) && allowContentType(config.ContentTypes, c.ContentType()) {
+			body = peekBody(c.Request, config.MaxBodyBytes)
 		}
-		c.Request.Body = ioutil.NopCloser(bytes.NewBuffer(body))
 
 		// Process request
 		c.Next()
 
 		_, skip := c.Get("SKIPLOG")
-		if skip {
+		if skip || (config.Skip != nil && config.Skip(c)) {
 			return
 		}
 
@@ -115,15 +226,18 @@ func Logger() gin.HandlerFunc {
 			path = path + "?" + raw
 		}
 
-		buf := blw.body.Bytes()
+		var buf []byte
+		if allowContentType(config.ContentTypes, filterFlags(c.Writer.Header().Get("Content-Type"))) {
+			buf = redactor.redact(blw.body.Bytes())
+		}
 
-		_, cut := c.Get("CUTREQBODY")
+		if _, cut := c.Get("CUTREQBODY"); cut && len(body) > 256 {
+			body = body[0:256]
+		}
+		body = redactor.redact(body)
 
-		if cut {
-			logx.Ix(c, "[GIN]", "%v | %3d | %13v | %15s | {userId:%v} | %-7s %s  %s  %s  %s",
-				end.Format("2006/01/02 - 15:04:05"), statusCode, latency, clientIP, userID, method, path, comment, body[0:256], buf,
-			)
-			return
+		if headers := logHeaders(c, config.LogHeaders, config.RedactHeaders); headers != "" {
+			comment = strings.TrimSpace(comment + " " + headers)
 		}
 
 		logx.Ix(c, "[GIN]", "%v | %3d | %13v | %15s | {userId:%v} | %-7s %s  %s  %s  %s",
@@ -132,14 +246,99 @@ func Logger() gin.HandlerFunc {
 	}
 }
 
+// peekBody 读取最多limit字节用于日志, 原始body保持完整可读
+func peekBody(r *http.Request, limit int) []byte {
+	if limit <= 0 {
+		body, _ := ioutil.ReadAll(r.Body)
+		r.Body = ioutil.NopCloser(bytes.NewBuffer(body))
+		return body
+	}
+	head, _ := ioutil.ReadAll(io.LimitReader(r.Body, int64(limit)))
+	r.Body = readCloser{Reader: io.MultiReader(bytes.NewReader(head), r.Body), Closer: r.Body}
+	return head
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+func allowContentType(allows []string, contentType string) bool {
+	if len(allows) == 0 || contentType == "" {
+		return true
+	}
+	for _, t := range allows {
+		if strings.HasPrefix(contentType, t) {
+			return true
+		}
+	}
+	return false
+}
+
+func filterFlags(content string) string {
+	for i, char := range content {
+		if char == ' ' || char == ';' {
+			return content[:i]
+		}
+	}
+	return content
+}
+
+func logHeaders(c *gin.Context, names, redacts []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	items := make([]string, 0, len(names))
+	for _, name := range names {
+		value := c.GetHeader(name)
+		if value == "" {
+			continue
+		}
+		for _, r := range redacts {
+			if strings.EqualFold(r, name) {
+				value = redactedValue
+				break
+			}
+		}
+		items = append(items, name+"="+value)
+	}
+	if len(items) == 0 {
+		return ""
+	}
+	return "{headers:" + strings.Join(items, ",") + "}"
+}
+
 type bodyLogWriter struct {
 	gin.ResponseWriter
-	body *bytes.Buffer
+	body  *bytes.Buffer
+	limit int
 }
 
 func (w bodyLogWriter) Write(b []byte) (int, error) {
+	w.capture(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w bodyLogWriter) WriteString(s string) (int, error) {
+	w.capture([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+// capture SSE等流式响应不缓存, 避免长连接期间body无限增长
+func (w bodyLogWriter) capture(b []byte) {
+	if strings.HasPrefix(w.Header().Get("Content-Type"), ginx.SSEContentType) {
+		return
+	}
+	if w.limit > 0 {
+		left := w.limit - w.body.Len()
+		if left <= 0 {
+			return
+		}
+		if len(b) > left {
+			b = b[:left]
+		}
+	}
 	if _, err := w.body.Write(b); err != nil {
 		fmt.Printf("bodyLogWriter err:%v", err)
 	}
-	return w.ResponseWriter.Write(b)
 }
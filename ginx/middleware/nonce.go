@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ytf606/golibs/service/redis"
+)
+
+// NonceStore 记录已使用过的nonce, 用于签名防重放
+type NonceStore interface {
+	// Add 在ttl内首次出现返回true, 重复出现返回false
+	Add(ctx context.Context, key string, ttl time.Duration) (bool, error)
+}
+
+type memoryNonceStore struct {
+	mu      sync.Mutex
+	items   map[string]time.Time
+	lastGC  time.Time
+	gcEvery time.Duration
+}
+
+// NewMemoryNonceStore 单机内存实现, 多实例部署时请使用NewRedisNonceStore
+func NewMemoryNonceStore() NonceStore {
+	return &memoryNonceStore{
+		items:   make(map[string]time.Time),
+		lastGC:  time.Now(),
+		gcEvery: time.Minute,
+	}
+}
+
+func (s *memoryNonceStore) Add(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.lastGC) > s.gcEvery {
+		for k, expire := range s.items {
+			if now.After(expire) {
+				delete(s.items, k)
+			}
+		}
+		s.lastGC = now
+	}
+
+	if expire, ok := s.items[key]; ok && now.Before(expire) {
+		return false, nil
+	}
+	s.items[key] = now.Add(ttl)
+	return true, nil
+}
+
+type redisNonceStore struct {
+	client *redis.Ins
+	prefix string
+}
+
+// NewRedisNonceStore 基于redis SETNX实现, client可通过redis.DefaultManager.Get(name)获取
+func NewRedisNonceStore(client *redis.Ins, prefix string) NonceStore {
+	if prefix == "" {
+		prefix = "golibs:sign:nonce:"
+	}
+	return &redisNonceStore{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+func (s *redisNonceStore) Add(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	return s.client.SetNX(ctx, s.prefix+key, 1, ttl).Result()
+}
@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+const redactedValue = "***"
+
+// redactor 对日志中的body按字段脱敏
+type redactor struct {
+	paths map[string]bool
+	keys  map[string]bool
+	// json解析失败(如被截断)或表单body时按字段名正则替换
+	jsonPattern *regexp.Regexp
+	formPattern *regexp.Regexp
+}
+
+func newRedactor(keys []string) *redactor {
+	if len(keys) == 0 {
+		return nil
+	}
+	r := &redactor{
+		paths: make(map[string]bool),
+		keys:  make(map[string]bool),
+	}
+	names := make([]string, 0, len(keys))
+	for _, k := range keys {
+		k = strings.ToLower(strings.TrimSpace(k))
+		if k == "" {
+			continue
+		}
+		if strings.Contains(k, ".") {
+			r.paths[k] = true
+		} else {
+			r.keys[k] = true
+		}
+		names = append(names, regexp.QuoteMeta(k[strings.LastIndex(k, ".")+1:]))
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	alt := strings.Join(names, "|")
+	r.jsonPattern = regexp.MustCompile(`(?i)"(` + alt + `)"\s*:\s*("(?:[^"\\]|\\.)*"?|[^,}\]\s]*)`)
+	r.formPattern = regexp.MustCompile(`(?i)(^|&)(` + alt + `)=[^&]*`)
+	return r
+}
+
+func (r *redactor) redact(body []byte) []byte {
+	if r == nil || len(body) == 0 {
+		return body
+	}
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+		var v interface{}
+		d := json.NewDecoder(bytes.NewReader(trimmed))
+		d.UseNumber()
+		if err := d.Decode(&v); err == nil {
+			buf := new(bytes.Buffer)
+			enc := json.NewEncoder(buf)
+			enc.SetEscapeHTML(false)
+			if err := enc.Encode(r.walk(v, "")); err == nil {
+				return bytes.TrimRight(buf.Bytes(), "\n")
+			}
+		}
+		return r.jsonPattern.ReplaceAll(body, []byte(`"$1":"`+redactedValue+`"`))
+	}
+	return r.formPattern.ReplaceAll(body, []byte("${1}${2}="+redactedValue))
+}
+
+func (r *redactor) walk(v interface{}, path string) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, item := range t {
+			p := strings.ToLower(k)
+			if path != "" {
+				p = path + "." + p
+			}
+			if r.keys[strings.ToLower(k)] || r.paths[p] {
+				t[k] = redactedValue
+				continue
+			}
+			t[k] = r.walk(item, p)
+		}
+	case []interface{}:
+		// 数组下标不参与路径匹配, 如list.mobile匹配list中每个元素的mobile
+		for i, item := range t {
+			t[i] = r.walk(item, path)
+		}
+	}
+	return v
+}
@@ -0,0 +1,153 @@
+package middleware
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/ytf606/golibs/logx/log4go"
+	"github.com/ytf606/golibs/logx/logtrace"
+)
+
+func TestRedact(t *testing.T) {
+	r := newRedactor([]string{"password", "user.mobile", "list.token"})
+
+	// 不含.的字段匹配任意层级, 路径只匹配对应位置, 数组元素按所在字段匹配
+	body := `{"password":"p1","user":{"mobile":"138","password":"p2","name":"n"},"mobile":"139","list":[{"token":"t1"},{"token":"t2","id":1}]}`
+	assert.JSONEq(t,
+		`{"password":"***","user":{"mobile":"***","password":"***","name":"n"},"mobile":"139","list":[{"token":"***"},{"token":"***","id":1}]}`,
+		string(r.redact([]byte(body))))
+
+	// 截断的json按字段名替换
+	assert.Equal(t, `{"password":"***","user":{"mobile":"***","na`,
+		string(r.redact([]byte(`{"password":"p\"1","user":{"mobile":138,"na`))))
+
+	// 表单
+	assert.Equal(t, "name=a&password=***&mobile=***",
+		string(r.redact([]byte("name=a&password=123&mobile=138"))))
+	assert.Equal(t, "plain text", string(r.redact([]byte("plain text"))))
+
+	var nilRedactor *redactor
+	assert.Equal(t, "password=1", string(nilRedactor.redact([]byte("password=1"))))
+	assert.Nil(t, newRedactor([]string{" "}))
+}
+
+func TestPeekBody(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("0123456789"))
+	assert.Equal(t, "0123", string(peekBody(req, 4)))
+	rest, _ := ioutil.ReadAll(req.Body)
+	assert.Equal(t, "0123456789", string(rest))
+
+	req = httptest.NewRequest(http.MethodPost, "/", strings.NewReader("0123456789"))
+	assert.Equal(t, "0123456789", string(peekBody(req, 0)))
+	rest, _ = ioutil.ReadAll(req.Body)
+	assert.Equal(t, "0123456789", string(rest))
+
+	assert.True(t, allowContentType(nil, "image/png"))
+	assert.True(t, allowContentType([]string{"text/"}, "text/plain"))
+	assert.True(t, allowContentType([]string{"text/"}, ""))
+	assert.False(t, allowContentType([]string{"text/"}, "image/png"))
+	assert.Equal(t, "application/json", filterFlags("application/json; charset=utf-8"))
+}
+
+type lineWriter struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (w *lineWriter) LogWrite(rec *log4go.LogRecord) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.lines = append(w.lines, rec.Message)
+}
+
+func (w *lineWriter) Close() {}
+
+func (w *lineWriter) find(prefix string) string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, line := range w.lines {
+		if strings.HasPrefix(line, prefix) {
+			return line
+		}
+	}
+	return ""
+}
+
+func TestLoggerRedact(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := &lineWriter{}
+	log4go.Global = log4go.Logger{"test": &log4go.Filter{Level: log4go.DEBUG, LogWriter: w}}
+	defer func() { log4go.Global = log4go.NewDefaultLogger(log4go.DEBUG) }()
+
+	config := DefaultLoggerConfig()
+	config.MaxBodyBytes = 64
+	config.LogHeaders = []string{"Authorization", "X-Client"}
+	var meta map[string]string
+	r := gin.New()
+	r.Use(LoggerWithConfig(config), LoggerMiddlewareWithConfig(TraceBodyConfig{
+		MaxBytes:     config.MaxBodyBytes,
+		ContentTypes: config.ContentTypes,
+		RedactKeys:   config.RedactKeys,
+	}))
+	r.POST("/login", func(c *gin.Context) {
+		meta = logtrace.ExtractTraceNodeFromContext(c).ForkMap()
+		body, _ := ioutil.ReadAll(c.Request.Body)
+		c.String(http.StatusOK, "len=%d", len(body))
+	})
+	do := func(path, contentType, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("Authorization", "Bearer abc")
+		req.Header.Set("X-Client", "ios")
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	body := `{"user":"u","password":"secret","token":"tk"}`
+	rec := do("/login", "application/json", body)
+	assert.Equal(t, fmt.Sprintf("len=%d", len(body)), rec.Body.String())
+	assert.Equal(t, `{"password":"***","token":"***","user":"u"}`, meta["x_request_param"])
+	line := w.find("[GIN]")
+	assert.NotContains(t, line, "secret")
+	assert.Contains(t, line, "{headers:Authorization=***,X-Client=ios}")
+
+	// 超过MaxBodyBytes的json截断后按字符串记录, handler仍能读到完整body
+	long := `{"password":"secret","data":"` + strings.Repeat("x", 100) + `"}`
+	rec = do("/login", "application/json", long)
+	assert.Equal(t, fmt.Sprintf("len=%d", len(long)), rec.Body.String())
+	assert.Empty(t, meta["x_request_param"])
+	assert.Contains(t, meta["request_param"], `\"password\":\"***\"`)
+	assert.NotContains(t, meta["request_param"], "secret")
+	assert.Less(t, len(meta["request_param"]), 100)
+
+	// 不在ContentTypes中的body不记录
+	do("/login", "application/octet-stream", "binary-password=1")
+	assert.Equal(t, `""`, meta["request_param"])
+
+	// CUTREQBODY只记录前256字节
+	cut := gin.New()
+	cut.Use(LoggerWithConfig(DefaultLoggerConfig()))
+	cut.POST("/cut", func(c *gin.Context) {
+		c.Set("CUTREQBODY", true)
+		c.Status(http.StatusOK)
+	})
+	w.lines = nil
+	cut.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/cut", strings.NewReader(strings.Repeat("y", 300))))
+	assert.Contains(t, w.find("[GIN]"), strings.Repeat("y", 256))
+	assert.NotContains(t, w.find("[GIN]"), strings.Repeat("y", 257))
+}
+
+func TestDefaultTraceBodyConfig(t *testing.T) {
+	config := DefaultTraceBodyConfig()
+	assert.Equal(t, DefaultTraceBodyBytes, config.MaxBytes)
+	assert.Equal(t, DefaultLoggerConfig().ContentTypes, config.ContentTypes)
+	assert.Equal(t, DefaultLoggerConfig().RedactKeys, config.RedactKeys)
+}
@@ -2,13 +2,21 @@ package middleware
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io/ioutil"
+	"net"
+	"net/http"
 	"net/http/httputil"
+	"os"
 	"runtime"
-	"time"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/ytf606/golibs/errorx"
+	"github.com/ytf606/golibs/ginx"
+	"github.com/ytf606/golibs/logx"
+	"github.com/ytf606/golibs/logx/logutils"
 )
 
 var (
@@ -25,21 +33,80 @@ func SkipLogInfo() gin.HandlerFunc {
 	}
 }
 
+// RecoveryFunc 发生panic时的回调, 可用于告警
+type RecoveryFunc func(c *gin.Context, err interface{}, stack []byte)
+
+// 请求dump中需要脱敏的header
+var recoverySensitiveHeaders = []string{"Authorization", "Cookie", "Set-Cookie", "Token", "AUTH-SIGN"}
+
+// 日志按行切分, 堆栈压成一行输出
+var stackReplacer = strings.NewReplacer("\n\t", " ", "\n", " | ")
+
 // Recovery - revover middleware
 func Recovery() gin.HandlerFunc {
+	return RecoveryWithHandler(nil)
+}
+
+// RecoveryWithHandler panic通过logx记录(携带logtrace元数据、堆栈和脱敏后的请求),
+// 并以errorx.ErrPanicRecover响应; 客户端断开导致的broken pipe只记录告警, 不再写响应
+func RecoveryWithHandler(handle RecoveryFunc) gin.HandlerFunc {
+	logTag := "http.middleware.recovery"
 	return func(c *gin.Context) {
 		defer func() {
 			if err := recover(); err != nil {
+				httprequest := dumpRequest(c.Request)
+				if brokenPipe(err) {
+					logx.Wx(c, logTag, "connection broken err:%v, request:%s", err, httprequest)
+					if e, ok := err.(error); ok {
+						c.Error(e)
+					}
+					c.Abort()
+					return
+				}
+
 				stack := stack(3)
-				httprequest, _ := httputil.DumpRequest(c.Request, false)
-				fmt.Println("[Recovery]", " %s panic recovered:\n%s\n%s\n%s", time.Now().Format("2006/01/02 - 15:04:05"), string(httprequest), err, string(stack))
-				c.AbortWithStatus(500)
+				logx.Ex(c, logTag, "panic recovered err:%v, request:%s, stack:%s",
+					err, httprequest, stackReplacer.Replace(string(stack)))
+				if handle != nil {
+					handle(c, err, stack)
+				}
+				ginx.ErrResponse(c, errorx.ErrPanicRecover)
+				c.Abort()
 			}
 		}()
 		c.Next()
 	}
 }
 
+// brokenPipe 判断是否为客户端断开连接
+func brokenPipe(err interface{}) bool {
+	ne, ok := err.(*net.OpError)
+	if !ok {
+		return false
+	}
+	se, ok := ne.Err.(*os.SyscallError)
+	if !ok {
+		return false
+	}
+	msg := strings.ToLower(se.Error())
+	return strings.Contains(msg, "broken pipe") || strings.Contains(msg, "connection reset by peer")
+}
+
+// dumpRequest 不含body的请求dump, 敏感header做脱敏处理
+func dumpRequest(r *http.Request) string {
+	if r == nil {
+		return ""
+	}
+	req := r.Clone(context.Background())
+	for _, h := range recoverySensitiveHeaders {
+		if req.Header.Get(h) != "" {
+			req.Header.Set(h, "*")
+		}
+	}
+	dump, _ := httputil.DumpRequest(req, false)
+	return logutils.Filter(string(dump), " ")
+}
+
 func stack(skip int) []byte {
 	buf := new(bytes.Buffer) // the returned data
 	// As we loop, we open files and read them. These variables record the currently
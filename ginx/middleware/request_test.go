@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/ytf606/golibs/errorx"
+	"github.com/ytf606/golibs/logx/log4go"
+)
+
+func TestRecoveryWithHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	lw := &lineWriter{}
+	log4go.Global = log4go.Logger{"test": &log4go.Filter{Level: log4go.DEBUG, LogWriter: lw}}
+	defer func() { log4go.Global = log4go.NewDefaultLogger(log4go.DEBUG) }()
+	var (
+		recovered interface{}
+		stack     []byte
+		handled   int
+		aborted   bool
+		written   bool
+		errs      []string
+	)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Next()
+		aborted, written, errs = c.IsAborted(), c.Writer.Written(), c.Errors.Errors()
+	})
+	r.Use(RecoveryWithHandler(func(c *gin.Context, err interface{}, s []byte) {
+		handled++
+		recovered, stack = err, s
+	}))
+	r.GET("/panic", func(c *gin.Context) { panic("boom") })
+	r.GET("/broken", func(c *gin.Context) {
+		panic(&net.OpError{Op: "write", Net: "tcp", Err: os.NewSyscallError("write", syscall.EPIPE)})
+	})
+	do := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "secret")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do("/panic")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	var res map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	assert.EqualValues(t, errorx.GinxPanicRecoverErr, res["code"])
+	assert.Equal(t, 1, handled)
+	assert.Equal(t, "boom", recovered)
+	assert.NotEmpty(t, stack)
+
+	// 默认处理
+	r2 := gin.New()
+	r2.Use(Recovery())
+	r2.GET("/panic", func(c *gin.Context) { panic("boom") })
+	w = httptest.NewRecorder()
+	r2.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/panic", nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	assert.EqualValues(t, errorx.GinxPanicRecoverErr, res["code"])
+
+	assert.Contains(t, lw.find("http.middleware.recovery"), "stack:")
+
+	// 客户端断开不写响应, 也不回调, 只记录不带堆栈的告警
+	lw.lines = nil
+	w = do("/broken")
+	assert.False(t, written)
+	assert.Empty(t, w.Body.String())
+	assert.Empty(t, w.Header())
+	assert.True(t, aborted)
+	if assert.Len(t, errs, 1) {
+		assert.Contains(t, errs[0], "broken pipe")
+	}
+	assert.Equal(t, 1, handled)
+	line := lw.find("http.middleware.recovery")
+	assert.Contains(t, line, "connection broken")
+	assert.Contains(t, line, "broken pipe")
+	assert.NotContains(t, line, "stack:")
+	assert.NotContains(t, line, "secret")
+	assert.Len(t, lw.lines, 1)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "secret")
+	assert.NotContains(t, dumpRequest(req), "secret")
+}
@@ -0,0 +1,205 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ytf606/golibs/errorx"
+	"github.com/ytf606/golibs/ginx"
+	"github.com/ytf606/golibs/logx"
+)
+
+// SecurityConfig 安全响应头配置, 可通过configx.ConfMapToStruct加载
+type SecurityConfig struct {
+	// HSTSMaxAge Strict-Transport-Security的max-age, 如8760h, 为0时不输出, 仅https请求输出
+	HSTSMaxAge time.Duration `ini:"hstsMaxAge"`
+
+	HSTSIncludeSubdomains bool `ini:"hstsIncludeSubdomains"`
+
+	HSTSPreload bool `ini:"hstsPreload"`
+
+	// ContentTypeNosniff 输出X-Content-Type-Options: nosniff
+	ContentTypeNosniff bool `ini:"contentTypeNosniff"`
+
+	// FrameOptions X-Frame-Options, DENY或SAMEORIGIN, 为空时不输出
+	FrameOptions string `ini:"frameOptions"`
+
+	// ContentSecurityPolicy 如default-src 'self', 为空时不输出
+	ContentSecurityPolicy string `ini:"contentSecurityPolicy"`
+
+	// CSPReportOnly 以Content-Security-Policy-Report-Only输出, 用于灰度策略
+	CSPReportOnly bool `ini:"cspReportOnly"`
+
+	// ReferrerPolicy 如strict-origin-when-cross-origin, 为空时不输出
+	ReferrerPolicy string `ini:"referrerPolicy"`
+}
+
+// DefaultSecurityConfig -
+func DefaultSecurityConfig() SecurityConfig {
+	return SecurityConfig{
+		HSTSMaxAge:         365 * 24 * time.Hour,
+		ContentTypeNosniff: true,
+		FrameOptions:       "DENY",
+		ReferrerPolicy:     "strict-origin-when-cross-origin",
+	}
+}
+
+// SecurityHeadersMiddleware 输出HSTS、X-Content-Type-Options、X-Frame-Options、CSP和Referrer-Policy
+func SecurityHeadersMiddleware(config SecurityConfig) gin.HandlerFunc {
+	hsts := ""
+	if config.HSTSMaxAge > 0 {
+		hsts = "max-age=" + strconv.FormatInt(int64(config.HSTSMaxAge/time.Second), 10)
+		if config.HSTSIncludeSubdomains {
+			hsts += "; includeSubDomains"
+		}
+		if config.HSTSPreload {
+			hsts += "; preload"
+		}
+	}
+	cspHeader := "Content-Security-Policy"
+	if config.CSPReportOnly {
+		cspHeader = "Content-Security-Policy-Report-Only"
+	}
+	return func(c *gin.Context) {
+		header := c.Writer.Header()
+		if hsts != "" && isHTTPS(c) {
+			header.Set("Strict-Transport-Security", hsts)
+		}
+		if config.ContentTypeNosniff {
+			header.Set("X-Content-Type-Options", "nosniff")
+		}
+		if config.FrameOptions != "" {
+			header.Set("X-Frame-Options", config.FrameOptions)
+		}
+		if config.ContentSecurityPolicy != "" {
+			header.Set(cspHeader, config.ContentSecurityPolicy)
+		}
+		if config.ReferrerPolicy != "" {
+			header.Set("Referrer-Policy", config.ReferrerPolicy)
+		}
+	}
+}
+
+func isHTTPS(c *gin.Context) bool {
+	return c.Request.TLS != nil || strings.EqualFold(c.GetHeader("X-Forwarded-Proto"), "https")
+}
+
+// CSRFTokenKey gin.Context中保存csrf token的key
+const CSRFTokenKey = "golibs_csrf_token"
+
+// CSRFConfig double submit cookie配置, 可通过configx.ConfMapToStruct加载
+type CSRFConfig struct {
+	// CookieName 默认csrf_token
+	CookieName string `ini:"cookieName"`
+
+	// HeaderName 默认X-CSRF-Token
+	HeaderName string `ini:"headerName"`
+
+	// FormField header中没有token时从表单读取, 默认_csrf
+	FormField string `ini:"formField"`
+
+	CookieDomain string `ini:"cookieDomain"`
+
+	// CookiePath 默认/
+	CookiePath string `ini:"cookiePath"`
+
+	// CookieMaxAge cookie有效期(秒), 默认43200
+	CookieMaxAge int `ini:"cookieMaxAge"`
+
+	CookieSecure bool `ini:"cookieSecure"`
+
+	// CookieSameSite lax、strict或none, 默认lax
+	CookieSameSite string `ini:"cookieSameSite"`
+
+	// SkipPaths 不校验的路由, 以*结尾的按前缀匹配
+	SkipPaths []string `ini:"skipPaths"`
+}
+
+// CSRFMiddleware double submit cookie方式的csrf校验
+// 安全方法(GET/HEAD/OPTIONS/TRACE)下发token cookie, 其余方法要求header或表单中的token与cookie一致
+// cookie不设置HttpOnly, 前端需读取后放入header; 服务端渲染页面可通过CSRFToken获取
+func CSRFMiddleware(config CSRFConfig) gin.HandlerFunc {
+	logTag := "http.middleware.csrf"
+	if config.CookieName == "" {
+		config.CookieName = "csrf_token"
+	}
+	if config.HeaderName == "" {
+		config.HeaderName = "X-CSRF-Token"
+	}
+	if config.FormField == "" {
+		config.FormField = "_csrf"
+	}
+	if config.CookiePath == "" {
+		config.CookiePath = "/"
+	}
+	if config.CookieMaxAge <= 0 {
+		config.CookieMaxAge = 12 * 3600
+	}
+	sameSite := http.SameSiteLaxMode
+	switch strings.ToLower(config.CookieSameSite) {
+	case "strict":
+		sameSite = http.SameSiteStrictMode
+	case "none":
+		sameSite = http.SameSiteNoneMode
+	}
+
+	return func(c *gin.Context) {
+		token, _ := c.Cookie(config.CookieName)
+		if !validCSRFToken(token) {
+			token = ""
+		}
+
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		default:
+			if len(config.SkipPaths) > 0 && matchRouter(config.SkipPaths, c.FullPath(), c.Request.URL.Path) {
+				break
+			}
+			sent := c.GetHeader(config.HeaderName)
+			if sent == "" {
+				sent = c.PostForm(config.FormField)
+			}
+			if token == "" || subtle.ConstantTimeCompare([]byte(sent), []byte(token)) != 1 {
+				logx.Wx(c, logTag, "csrf token mismatch path:%s, cookie:%t, sent:%t", c.Request.URL.Path, token != "", sent != "")
+				ginx.ErrResponse(c, errorx.ErrCSRFToken)
+				c.Abort()
+				return
+			}
+		}
+
+		if token == "" {
+			token = newCSRFToken()
+			c.SetSameSite(sameSite)
+			c.SetCookie(config.CookieName, token, config.CookieMaxAge, config.CookiePath, config.CookieDomain, config.CookieSecure, false)
+		}
+		c.Set(CSRFTokenKey, token)
+		c.Next()
+	}
+}
+
+// CSRFToken 当前请求的csrf token, 用于渲染表单隐藏域
+func CSRFToken(c *gin.Context) string {
+	return c.GetString(CSRFTokenKey)
+}
+
+func newCSRFToken() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+func validCSRFToken(token string) bool {
+	if len(token) != 64 {
+		return false
+	}
+	_, err := hex.DecodeString(token)
+	return err == nil
+}
@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCSRFMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(SecurityHeadersMiddleware(DefaultSecurityConfig()), CSRFMiddleware(CSRFConfig{}))
+	r.GET("/form", func(c *gin.Context) { c.String(http.StatusOK, CSRFToken(c)) })
+	r.POST("/save", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/form", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"))
+	assert.Equal(t, "DENY", w.Header().Get("X-Frame-Options"))
+	assert.Empty(t, w.Header().Get("Strict-Transport-Security"))
+	cookies := w.Result().Cookies()
+	assert.Len(t, cookies, 1)
+	token := cookies[0].Value
+	assert.Equal(t, token, w.Body.String())
+
+	post := func(header string) int {
+		req := httptest.NewRequest(http.MethodPost, "/save", nil)
+		req.AddCookie(&http.Cookie{Name: "csrf_token", Value: token})
+		if header != "" {
+			req.Header.Set("X-CSRF-Token", header)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	assert.Equal(t, http.StatusOK, post(token))
+	assert.Equal(t, http.StatusForbidden, post(""))
+	assert.Equal(t, http.StatusForbidden, post(newCSRFToken()))
+}
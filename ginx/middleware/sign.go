@@ -1,15 +1,27 @@
 package middleware
 
 import (
+	"bytes"
 	"crypto/md5"
 	"encoding/hex"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/ytf606/golibs/logx"
+	"github.com/ytf606/golibs/pkg/signx"
 )
 
+// DefaultSignExpire v2签名未配置Expire时的默认有效期(秒)
+const DefaultSignExpire int64 = 300
+
+// DefaultSignMaxBodyBytes v2签名未配置MaxBodyBytes时参与签名的body上限
+const DefaultSignMaxBodyBytes int64 = 10 << 20
+
 // SignItem App签名
 type SignItem struct {
 	Name    string   `json:"name"`
@@ -18,58 +30,171 @@ type SignItem struct {
 	AppKey  string   `json:"appKey"`
 	Expire  int64    `json:"expire"`
 	Routers []string `json:"routers"`
+	// MaxBodyBytes v2签名读取body的上限, 超出返回413
+	MaxBodyBytes int64 `json:"maxBodyBytes"`
+}
+
+// AllowRouter 校验路由白名单, Routers为空时不限制, 以*结尾的按前缀匹配
+func (s SignItem) AllowRouter(fullPath, path string) bool {
+	return len(s.Routers) == 0 || matchRouter(s.Routers, fullPath, path)
+}
+
+// matchRouter 匹配gin注册路由或实际path, 以*结尾的按前缀匹配
+func matchRouter(routers []string, fullPath, path string) bool {
+	for _, r := range routers {
+		if r == fullPath || r == path {
+			return true
+		}
+		if strings.HasSuffix(r, "*") && strings.HasPrefix(path, strings.TrimSuffix(r, "*")) {
+			return true
+		}
+	}
+	return false
 }
 
 // CheckSign 签名校验
 func CheckSignMiddlerware(opts map[string]SignItem) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		if opts == nil {
-			c.String(http.StatusForbidden, "403 Forbidden")
-			c.Abort()
+			signForbidden(c)
 			return
 		}
-		appSource := c.GetHeader("AUTH-SOURCE")
+		appSource := c.GetHeader(signx.HeaderSource)
 		if appSource == "" {
-			c.String(http.StatusForbidden, "403 Forbidden")
-			c.Abort()
+			signForbidden(c)
 			return
 		}
-		appTime := c.GetHeader("AUTH-TIME")
+		appTime := c.GetHeader(signx.HeaderTime)
 		if appTime == "" {
-			c.String(http.StatusForbidden, "403 Forbidden")
-			c.Abort()
+			signForbidden(c)
 			return
 		}
-		appSign := c.GetHeader("AUTH-SIGN")
+		appSign := c.GetHeader(signx.HeaderSign)
 		if appSign == "" {
-			c.String(http.StatusForbidden, "403 Forbidden")
-			c.Abort()
+			signForbidden(c)
 			return
 		}
 		signItem, ok := opts[appSource]
 		if !ok {
-			c.String(http.StatusForbidden, "403 Forbidden")
-			c.Abort()
+			signForbidden(c)
+			return
+		}
+		if !signItem.AllowRouter(c.FullPath(), c.Request.URL.Path) {
+			signForbidden(c)
 			return
 		}
 		iTime, err := strconv.ParseInt(appTime, 10, 64)
 		if err != nil {
-			c.String(http.StatusForbidden, "403 Forbidden")
-			c.Abort()
+			signForbidden(c)
 			return
 		}
 		if iTime+signItem.Expire < time.Now().Unix() {
-			c.String(http.StatusForbidden, "403 Forbidden")
-			c.Abort()
+			signForbidden(c)
 			return
 		}
 		signBytes := md5.Sum([]byte(signItem.AppID + signItem.AppKey + "&" + appTime))
 		signTxt := hex.EncodeToString(signBytes[:])
-		if signTxt != appSign {
-			c.String(http.StatusForbidden, "403 Forbidden")
-			c.Abort()
+		if !signx.Equal(appSign, signTxt) {
+			signForbidden(c)
 			return
 		}
 		c.Next()
 	}
 }
+
+// CheckSignV2Middlerware HMAC-SHA256签名校验
+// 签名覆盖method、path、排序后的query、body摘要、时间戳和nonce, 客户端见request.NewSigner
+// nonce在有效期内只能使用一次, store为nil时使用单机内存存储
+func CheckSignV2Middlerware(opts map[string]SignItem, store NonceStore) gin.HandlerFunc {
+	logTag := "http.middleware.sign"
+	if store == nil {
+		store = NewMemoryNonceStore()
+	}
+	return func(c *gin.Context) {
+		appSource := c.GetHeader(signx.HeaderSource)
+		appTime := c.GetHeader(signx.HeaderTime)
+		appNonce := c.GetHeader(signx.HeaderNonce)
+		appSign := c.GetHeader(signx.HeaderSign)
+		if appSource == "" || appTime == "" || appNonce == "" || appSign == "" {
+			logx.Wx(c, logTag, "sign header missing source:%s, time:%s, nonce:%s", appSource, appTime, appNonce)
+			signForbidden(c)
+			return
+		}
+		signItem, ok := opts[appSource]
+		if !ok {
+			logx.Wx(c, logTag, "sign source not found source:%s", appSource)
+			signForbidden(c)
+			return
+		}
+		if !signItem.AllowRouter(c.FullPath(), c.Request.URL.Path) {
+			logx.Wx(c, logTag, "sign router not allowed source:%s, path:%s", appSource, c.Request.URL.Path)
+			signForbidden(c)
+			return
+		}
+
+		expire := signItem.Expire
+		if expire <= 0 {
+			expire = DefaultSignExpire
+		}
+		iTime, err := strconv.ParseInt(appTime, 10, 64)
+		if err != nil {
+			signForbidden(c)
+			return
+		}
+		if now := time.Now().Unix(); iTime+expire < now || iTime-expire > now {
+			logx.Wx(c, logTag, "sign expired source:%s, time:%s", appSource, appTime)
+			signForbidden(c)
+			return
+		}
+
+		maxBody := signItem.MaxBodyBytes
+		if maxBody <= 0 {
+			maxBody = DefaultSignMaxBodyBytes
+		}
+		var body []byte
+		if c.Request.Body != nil {
+			body, err = ioutil.ReadAll(io.LimitReader(c.Request.Body, maxBody+1))
+			if err != nil {
+				logx.Wx(c, logTag, "sign read body failed err:%+v, source:%s", err, appSource)
+				signReject(c, http.StatusBadRequest)
+				return
+			}
+			if int64(len(body)) > maxBody {
+				logx.Wx(c, logTag, "sign body too large source:%s, limit:%d", appSource, maxBody)
+				signReject(c, http.StatusRequestEntityTooLarge)
+				return
+			}
+		}
+		c.Request.Body = ioutil.NopCloser(bytes.NewBuffer(body))
+
+		canonical := signx.CanonicalString(c.Request.Method, c.Request.URL.Path, c.Request.URL.Query(), body, appTime, appNonce)
+		if !signx.Equal(appSign, signx.Sign(signItem.AppID, signItem.AppKey, canonical)) {
+			logx.Wx(c, logTag, "sign mismatch source:%s, path:%s", appSource, c.Request.URL.Path)
+			signForbidden(c)
+			return
+		}
+
+		// 签名通过后再记录nonce, 避免伪造请求占用nonce
+		fresh, err := store.Add(c, appSource+":"+appNonce, time.Duration(2*expire)*time.Second)
+		if err != nil {
+			logx.Ex(c, logTag, "sign nonce store failed err:%+v, source:%s", err, appSource)
+			signForbidden(c)
+			return
+		}
+		if !fresh {
+			logx.Wx(c, logTag, "sign nonce replayed source:%s, nonce:%s", appSource, appNonce)
+			signForbidden(c)
+			return
+		}
+		c.Next()
+	}
+}
+
+func signForbidden(c *gin.Context) {
+	signReject(c, http.StatusForbidden)
+}
+
+func signReject(c *gin.Context, status int) {
+	c.String(status, strconv.Itoa(status)+" "+http.StatusText(status))
+	c.Abort()
+}
@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/ytf606/golibs/request"
+)
+
+func TestCheckSignV2(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	opts := map[string]SignItem{
+		"app": {
+			Source:  "app",
+			AppID:   "100",
+			AppKey:  "secret",
+			Expire:  60,
+			Routers: []string{"/order/*"},
+		},
+	}
+	r := gin.New()
+	r.Use(CheckSignV2Middlerware(opts, NewMemoryNonceStore()))
+	r.POST("/order/create", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+	r.POST("/user/create", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+
+	do := func(target, body string, headers map[string]string) int {
+		req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	signer := request.NewSigner("app", "100", "secret")
+	body := `{"id":1}`
+	headers, err := signer.Headers(http.MethodPost, "http://svc/order/create?b=2&a=1", []byte(body))
+	assert.Nil(t, err)
+
+	assert.Equal(t, http.StatusOK, do("/order/create?a=1&b=2", body, headers))
+	assert.Equal(t, http.StatusForbidden, do("/order/create?a=1&b=2", body, headers), "replayed nonce")
+
+	headers, _ = signer.Headers(http.MethodPost, "http://svc/order/create", []byte(body))
+	assert.Equal(t, http.StatusForbidden, do("/order/create", `{"id":2}`, headers), "tampered body")
+
+	headers, _ = signer.Headers(http.MethodPost, "http://svc/user/create", []byte(body))
+	assert.Equal(t, http.StatusForbidden, do("/user/create", body, headers), "router not allowed")
+
+	headers, _ = request.NewSigner("app", "100", "wrong").Headers(http.MethodPost, "http://svc/order/create", []byte(body))
+	assert.Equal(t, http.StatusForbidden, do("/order/create", body, headers), "wrong key")
+}
+
+type errReader struct{}
+
+func (errReader) Read([]byte) (int, error) { return 0, errors.New("read failed") }
+
+func TestCheckSignV2Body(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	opts := map[string]SignItem{
+		"app": {Source: "app", AppID: "100", AppKey: "secret", Expire: 60, MaxBodyBytes: 8},
+	}
+	r := gin.New()
+	r.Use(CheckSignV2Middlerware(opts, nil))
+	r.POST("/order", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+	signer := request.NewSigner("app", "100", "secret")
+
+	do := func(body string, reader io.Reader) int {
+		headers, err := signer.Headers(http.MethodPost, "http://svc/order", []byte(body))
+		assert.Nil(t, err)
+		req := httptest.NewRequest(http.MethodPost, "/order", reader)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, do("12345678", strings.NewReader("12345678")))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, do("123456789", strings.NewReader("123456789")))
+	assert.Equal(t, http.StatusBadRequest, do("", errReader{}))
+}
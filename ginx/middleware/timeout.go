@@ -0,0 +1,228 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ytf606/golibs/errorx"
+	"github.com/ytf606/golibs/ginx"
+	"github.com/ytf606/golibs/logx"
+)
+
+// HeaderRequestTimeout 上游剩余超时时间(毫秒), request包发起调用时会自动透传
+const HeaderRequestTimeout = errorx.HeaderRequestTimeout
+
+// TimeoutMiddleware 为c.Request.Context()设置deadline并限制后续handler的执行时间
+// 上游带有X-Request-Timeout时取两者较小值, 业务需使用ginx.StdReqCtx才能让db、redis、request调用随之取消
+// 后续handler的响应先写入缓冲, 按时完成时原样输出; 到达deadline时立即返回errorx.ErrRequestTimeout(504)并丢弃之后的写入
+// 忽略ctx的handler会在后台执行到结束, 期间连接不会复用, SSE、文件下载等流式响应不要使用该中间件
+func TimeoutMiddleware(timeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		d := timeout
+		if v := c.GetHeader(HeaderRequestTimeout); v != "" {
+			if ms, err := strconv.ParseInt(v, 10, 64); err == nil {
+				upstream := time.Duration(ms) * time.Millisecond
+				if upstream <= 0 {
+					ginx.ErrResponse(c, errorx.ErrRequestTimeout)
+					c.Abort()
+					return
+				}
+				if d <= 0 || upstream < d {
+					d = upstream
+				}
+			}
+		}
+		if d <= 0 {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), d)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+
+		// 超时后handler仍在执行, 本协程只使用tc中的副本, 不再读写c
+		dst := c.Writer
+		keys := make(map[string]interface{}, len(c.Keys))
+		for k, v := range c.Keys {
+			keys[k] = v
+		}
+		tc := &gin.Context{Request: c.Request, Writer: dst, Keys: keys}
+		tw := newTimeoutWriter(dst)
+		c.Writer = tw
+
+		done := make(chan interface{}, 1)
+		go func() {
+			defer func() {
+				done <- recover()
+			}()
+			c.Next()
+		}()
+
+		var p interface{}
+		select {
+		case p = <-done:
+		case <-ctx.Done():
+			select {
+			case p = <-done:
+			default:
+				if tw.timeout() {
+					// handler已写出响应, 原样返回, 之后的写入丢弃
+					tw.flushTo(dst, true)
+					dst.Flush()
+				} else {
+					writeTimeout(tc)
+				}
+				logx.Wx(ginx.StdCtx(tc), "[ginx_timeout]", "request timeout after %s, path:%s", d, tc.Request.URL.Path)
+				p = <-done
+			}
+		}
+		c.Writer = dst
+		if p != nil {
+			if !tw.timedOut {
+				panic(p)
+			}
+			logx.Ex(ginx.StdCtx(c), "[ginx_timeout]", "panic after request timeout err:%v, stack:%s", p, debug.Stack())
+			return
+		}
+		if tw.timedOut {
+			c.Abort()
+			return
+		}
+
+		tw.flushTo(dst, false)
+		if ctx.Err() == context.DeadlineExceeded && !c.Writer.Written() {
+			ginx.ErrResponse(c, errorx.ErrRequestTimeout)
+			c.Abort()
+		}
+	}
+}
+
+// writeTimeout 先写入缓冲再带Content-Length输出, 客户端无需等待handler结束即可读完响应
+func writeTimeout(c *gin.Context) {
+	dst := c.Writer
+	buf := newTimeoutWriter(dst)
+	c.Writer = buf
+	ginx.ErrResponse(c, errorx.ErrRequestTimeout)
+	buf.flushTo(dst, true)
+	c.Writer = dst
+	dst.Flush()
+}
+
+// timeoutWriter 缓冲handler的响应, 超时后丢弃写入
+type timeoutWriter struct {
+	gin.ResponseWriter
+
+	mu       sync.Mutex
+	header   http.Header
+	body     bytes.Buffer
+	status   int
+	written  bool
+	timedOut bool
+}
+
+func newTimeoutWriter(w gin.ResponseWriter) *timeoutWriter {
+	return &timeoutWriter{ResponseWriter: w, header: make(http.Header), status: http.StatusOK}
+}
+
+// timeout 标记超时, 返回handler是否已写出响应
+func (w *timeoutWriter) timeout() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.timedOut = true
+	return w.written
+}
+
+// flushTo 将缓冲的header、状态码和body写入dst
+func (w *timeoutWriter) flushTo(dst gin.ResponseWriter, contentLength bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	h := dst.Header()
+	for k, v := range w.header {
+		h[k] = v
+	}
+	if contentLength {
+		h.Set("Content-Length", strconv.Itoa(w.body.Len()))
+	}
+	dst.WriteHeader(w.status)
+	if w.body.Len() > 0 {
+		dst.Write(w.body.Bytes())
+	} else if w.written {
+		dst.WriteHeaderNow()
+	}
+}
+
+func (w *timeoutWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut || code <= 0 {
+		return
+	}
+	w.status = code
+}
+
+func (w *timeoutWriter) WriteHeaderNow() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.written = true
+}
+
+func (w *timeoutWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	// 超时后的写入直接丢弃, 返回错误会使gin的render panic
+	if w.timedOut {
+		return len(b), nil
+	}
+	w.written = true
+	return w.body.Write(b)
+}
+
+func (w *timeoutWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *timeoutWriter) Status() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.status
+}
+
+func (w *timeoutWriter) Size() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.written {
+		return -1
+	}
+	return w.body.Len()
+}
+
+func (w *timeoutWriter) Written() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.written
+}
+
+// Flush 响应需完整缓冲, 不支持流式输出
+func (w *timeoutWriter) Flush() {}
+
+func (w *timeoutWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return nil, nil, errors.New("hijack is not supported under TimeoutMiddleware")
+}
+
+func (w *timeoutWriter) Pusher() http.Pusher {
+	return nil
+}
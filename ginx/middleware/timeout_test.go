@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/ytf606/golibs/errorx"
+)
+
+func TestTimeoutMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(TimeoutMiddleware(50 * time.Millisecond))
+	r.GET("/fast", func(c *gin.Context) {
+		c.Header("X-Test", "1")
+		c.String(http.StatusCreated, "ok")
+	})
+	// 忽略ctx, 超时后的写入被丢弃
+	r.GET("/slow", func(c *gin.Context) {
+		time.Sleep(300 * time.Millisecond)
+		c.String(http.StatusOK, "late")
+	})
+	// 写完响应后才超过deadline
+	r.GET("/wrote", func(c *gin.Context) {
+		c.String(http.StatusOK, "done")
+		<-c.Request.Context().Done()
+	})
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	get := func(path string, headers map[string]string) (*http.Response, string, time.Duration) {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+path, nil)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		start := time.Now()
+		resp, err := http.DefaultClient.Do(req)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		defer resp.Body.Close()
+		body, _ := ioutil.ReadAll(resp.Body)
+		return resp, string(body), time.Since(start)
+	}
+
+	resp, body, _ := get("/fast", nil)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Equal(t, "1", resp.Header.Get("X-Test"))
+	assert.Equal(t, "ok", body)
+
+	resp, body, cost := get("/slow", nil)
+	assert.Equal(t, http.StatusGatewayTimeout, resp.StatusCode)
+	assert.Less(t, int64(cost), int64(250*time.Millisecond))
+	var res map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(body), &res))
+	assert.EqualValues(t, errorx.GinxRequestTimeoutErr, res["code"])
+
+	resp, body, _ = get("/wrote", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "done", body)
+
+	// 上游剩余时间已耗尽
+	resp, _, _ = get("/fast", map[string]string{HeaderRequestTimeout: "0"})
+	assert.Equal(t, http.StatusGatewayTimeout, resp.StatusCode)
+}
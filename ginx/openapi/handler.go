@@ -0,0 +1,74 @@
+package openapi
+
+import (
+	"fmt"
+	"path"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ytf606/golibs/errorx"
+	"github.com/ytf606/golibs/ginx"
+)
+
+var (
+	ginContextType = reflect.TypeOf((*gin.Context)(nil))
+	errorType      = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// Handle 注册类型化handler并记录文档, fn形如func(c *gin.Context, req *Req) (*Resp, error)
+// 请求由ginx.Bind从uri、header、query和body统一绑定, 校验失败返回400
+// op.Request、op.Response为空时取fn的参数和返回值类型
+//
+//	spec.Handle(api, http.MethodPost, "/login", user.Login, openapi.Operation{
+//		Summary: "登录",
+//		Errors:  []error{errorx.ErrJwtTokenInvalid},
+//	})
+func (s *Spec) Handle(group *gin.RouterGroup, method, relativePath string, fn interface{}, op Operation, middlewares ...gin.HandlerFunc) gin.IRoutes {
+	fv := reflect.ValueOf(fn)
+	ft := fv.Type()
+	if ft.Kind() != reflect.Func || ft.NumIn() != 2 || ft.In(0) != ginContextType ||
+		ft.In(1).Kind() != reflect.Ptr || ft.In(1).Elem().Kind() != reflect.Struct ||
+		ft.NumOut() != 2 || ft.Out(1) != errorType {
+		panic(fmt.Sprintf("openapi: handler of %s %s must be func(*gin.Context, *Req) (Resp, error), got %s", method, relativePath, ft))
+	}
+	reqType := ft.In(1).Elem()
+	if op.Request == nil {
+		op.Request = reflect.New(reqType).Interface()
+	}
+	if op.Response == nil {
+		op.Response = reflect.Zero(ft.Out(0)).Interface()
+	}
+	code := op.BindErrCode
+	if code == 0 {
+		code = errorx.GinxParamBindErr
+	}
+	method = strings.ToUpper(method)
+	s.Annotate(method, joinPath(group.BasePath(), relativePath), op)
+
+	handler := func(c *gin.Context) {
+		req := reflect.New(reqType)
+		if err := ginx.BindCheck(c, req.Interface(), code); err != nil {
+			return
+		}
+		out := fv.Call([]reflect.Value{reflect.ValueOf(c), req})
+		if err, _ := out[1].Interface().(error); err != nil {
+			ginx.ErrResponse(c, err)
+			return
+		}
+		ginx.SuccResponse(c, out[0].Interface())
+	}
+	return group.Handle(method, relativePath, append(middlewares, handler)...)
+}
+
+// joinPath 与gin拼接group路径的规则一致, 保留结尾的/
+func joinPath(base, relative string) string {
+	if relative == "" {
+		return base
+	}
+	joined := path.Join(base, relative)
+	if strings.HasSuffix(relative, "/") && !strings.HasSuffix(joined, "/") {
+		return joined + "/"
+	}
+	return joined
+}
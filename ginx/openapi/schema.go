@@ -0,0 +1,267 @@
+package openapi
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+type schemaGen struct {
+	components map[string]*Schema
+}
+
+func newSchemaGen(components map[string]*Schema) *schemaGen {
+	return &schemaGen{components: components}
+}
+
+func typeOf(v interface{}) reflect.Type {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+// schema 命名的结构体放入components并返回$ref
+func (g *schemaGen) schema(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: g.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t, "json", nil)
+		}
+		name := schemaName(t)
+		if _, ok := g.components[name]; !ok {
+			// 先占位, 避免自引用的结构体无限递归
+			g.components[name] = &Schema{}
+			*g.components[name] = *g.object(t, "json", nil)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	}
+	return &Schema{}
+}
+
+// object 按tag取字段名生成对象schema, skip返回true的字段忽略
+func (g *schemaGen) object(t reflect.Type, tag string, skip func(f reflect.StructField) bool) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	g.fields(t, tag, skip, func(name string, f reflect.StructField, required bool) {
+		fs := g.schema(f.Type)
+		if fs.Ref != "" {
+			if desc := label(f); desc != "" {
+				fs = &Schema{AllOf: []*Schema{fs}, Description: desc}
+			}
+		} else {
+			applyBinding(fs, f)
+			fs.Description = label(f)
+		}
+		s.Properties[name] = fs
+		if required {
+			s.Required = append(s.Required, name)
+		}
+	})
+	return s
+}
+
+// fields 遍历导出字段, 匿名结构体字段展开
+func (g *schemaGen) fields(t reflect.Type, tag string, skip func(f reflect.StructField) bool, fn func(name string, f reflect.StructField, required bool)) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" && !f.Anonymous {
+			continue
+		}
+		if skip != nil && skip(f) {
+			continue
+		}
+		name := strings.Split(f.Tag.Get(tag), ",")[0]
+		if name == "-" {
+			continue
+		}
+		ft := f.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			g.fields(ft, tag, skip, fn)
+			continue
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fn(name, f, hasRule(f, "required"))
+	}
+}
+
+// parameters 按tag生成in位置的参数, 如uri=>path, header=>header, form=>query
+func (g *schemaGen) parameters(v interface{}, tag, in string) []*Parameter {
+	t := typeOf(v)
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+	var params []*Parameter
+	g.fields(t, tag, func(f reflect.StructField) bool {
+		// query参数未写form tag时使用字段名, path、header参数必须显式写tag
+		return tag != "form" && f.Tag.Get(tag) == "" && !f.Anonymous
+	}, func(name string, f reflect.StructField, required bool) {
+		if tag == "form" && (f.Tag.Get("uri") != "" || f.Tag.Get("header") != "") {
+			return
+		}
+		schema := g.schema(f.Type)
+		applyBinding(schema, f)
+		params = append(params, &Parameter{
+			Name:        name,
+			In:          in,
+			Description: label(f),
+			Required:    required || in == "path",
+			Schema:      schema,
+		})
+	})
+	return params
+}
+
+// requestBody json body, 排除uri、header参数
+func (g *schemaGen) requestBody(v interface{}) *RequestBody {
+	t := typeOf(v)
+	if t == nil {
+		return nil
+	}
+	var schema *Schema
+	if t.Kind() == reflect.Struct {
+		schema = g.object(t, "json", func(f reflect.StructField) bool {
+			return (f.Tag.Get("uri") != "" || f.Tag.Get("header") != "") && f.Tag.Get("json") == ""
+		})
+	} else {
+		schema = g.schema(t)
+	}
+	return &RequestBody{Required: true, Content: jsonContent(schema)}
+}
+
+func schemaName(t reflect.Type) string {
+	pkg := t.PkgPath()
+	if i := strings.LastIndex(pkg, "/"); i >= 0 {
+		pkg = pkg[i+1:]
+	}
+	if pkg == "" {
+		return t.Name()
+	}
+	return pkg + "." + t.Name()
+}
+
+func label(f reflect.StructField) string {
+	return strings.SplitN(f.Tag.Get("label"), ",", 2)[0]
+}
+
+func bindingRules(f reflect.StructField) []string {
+	rules := strings.Split(f.Tag.Get("binding"), ",")
+	for i, r := range rules {
+		// dive之后的规则作用于元素
+		if r == "dive" {
+			return rules[:i]
+		}
+	}
+	return rules
+}
+
+func hasRule(f reflect.StructField, name string) bool {
+	for _, r := range bindingRules(f) {
+		if r == name {
+			return true
+		}
+	}
+	return false
+}
+
+// applyBinding 将binding规则映射为schema约束
+func applyBinding(s *Schema, f reflect.StructField) {
+	for _, rule := range bindingRules(f) {
+		key, value := rule, ""
+		if i := strings.Index(rule, "="); i >= 0 {
+			key, value = rule[:i], rule[i+1:]
+		}
+		switch key {
+		case "email", "url", "uri", "uuid", "ipv4", "ipv6":
+			s.Format = key
+		case "datetime":
+			s.Format = "date-time"
+		case "oneof":
+			for _, item := range strings.Fields(value) {
+				if s.Type == "integer" {
+					if n, err := strconv.ParseInt(item, 10, 64); err == nil {
+						s.Enum = append(s.Enum, n)
+						continue
+					}
+				}
+				s.Enum = append(s.Enum, item)
+			}
+		case "len":
+			applyBound(s, value, true, true)
+		case "min", "gte":
+			applyBound(s, value, true, false)
+		case "max", "lte":
+			applyBound(s, value, false, true)
+		}
+	}
+}
+
+func applyBound(s *Schema, value string, min, max bool) {
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return
+	}
+	i := int(n)
+	switch s.Type {
+	case "integer", "number":
+		if min {
+			s.Minimum = &n
+		}
+		if max {
+			s.Maximum = &n
+		}
+	case "string":
+		if min {
+			s.MinLength = &i
+		}
+		if max {
+			s.MaxLength = &i
+		}
+	case "array":
+		if min {
+			s.MinItems = &i
+		}
+		if max {
+			s.MaxItems = &i
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ytf606/golibs/errorx"
+	"github.com/ytf606/golibs/ginx"
+)
+
+// Operation 路由的文档描述, Request/Response传结构体零值或指针
+type Operation struct {
+	Summary     string
+	Description string
+	Tags        []string
+	Request     interface{}
+	Response    interface{}
+	// Errors 可能返回的errorx错误, 按http状态码归组展示业务码
+	Errors     []error
+	Deprecated bool
+	// BindErrCode Handle绑定参数失败时的业务码, 默认errorx.GinxParamBindErr
+	BindErrCode int
+}
+
+// Spec 收集路由描述并生成OpenAPI 3文档
+type Spec struct {
+	Title   string
+	Version string
+	Servers []string
+
+	lock       sync.RWMutex
+	operations map[string]Operation
+}
+
+func NewSpec(title, version string) *Spec {
+	return &Spec{
+		Title:      title,
+		Version:    version,
+		operations: make(map[string]Operation),
+	}
+}
+
+// Annotate 为路由补充描述, path为完整路径(含group前缀), 与gin注册时的写法一致
+func (s *Spec) Annotate(method, path string, op Operation) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.operations[strings.ToUpper(method)+" "+path] = op
+}
+
+// Serve 在path上提供json文档, 文档在首次请求时根据engine已注册的路由生成
+func (s *Spec) Serve(engine *gin.Engine, path string) {
+	var (
+		once sync.Once
+		data []byte
+		err  error
+	)
+	engine.GET(path, func(c *gin.Context) {
+		once.Do(func() {
+			data, err = json.Marshal(s.Build(engine))
+		})
+		if err != nil {
+			ginx.ErrResponse(c, errorx.Wrap500Response(err, errorx.GinxResponseTypeErr, "openapi build failed"))
+			return
+		}
+		c.Data(http.StatusOK, "application/json; charset=utf-8", data)
+	})
+}
+
+// Build 遍历engine的路由生成文档, 未描述的路由只包含路径参数
+func (s *Spec) Build(engine *gin.Engine) *Document {
+	doc := &Document{
+		OpenAPI: "3.0.3",
+		Info:    Info{Title: s.Title, Version: s.Version},
+		Paths:   make(map[string]PathItem),
+		Components: Components{
+			Schemas: make(map[string]*Schema),
+		},
+	}
+	for _, url := range s.Servers {
+		doc.Servers = append(doc.Servers, Server{URL: url})
+	}
+	gen := newSchemaGen(doc.Components.Schemas)
+	doc.Components.Schemas["Response"] = responseSchema(nil)
+
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	for _, route := range engine.Routes() {
+		op, ok := s.operations[route.Method+" "+route.Path]
+		if !ok && route.Method == http.MethodHead {
+			continue
+		}
+		path, pathParams := convertPath(route.Path)
+		item, ok2 := doc.Paths[path]
+		if !ok2 {
+			item = make(PathItem)
+			doc.Paths[path] = item
+		}
+		item[strings.ToLower(route.Method)] = s.operation(gen, route, op, pathParams)
+	}
+	return doc
+}
+
+func (s *Spec) operation(gen *schemaGen, route gin.RouteInfo, op Operation, pathParams []string) *OperationObject {
+	o := &OperationObject{
+		Summary:     op.Summary,
+		Description: op.Description,
+		Tags:        op.Tags,
+		OperationId: operationId(route.Method, route.Path),
+		Deprecated:  op.Deprecated,
+		Responses:   make(map[string]*ResponseObject),
+	}
+
+	documented := make(map[string]bool)
+	if op.Request != nil {
+		for _, p := range gen.parameters(op.Request, "uri", "path") {
+			documented[p.Name] = true
+			o.Parameters = append(o.Parameters, p)
+		}
+		o.Parameters = append(o.Parameters, gen.parameters(op.Request, "header", "header")...)
+		if bodyMethod(route.Method) {
+			o.RequestBody = gen.requestBody(op.Request)
+		} else {
+			o.Parameters = append(o.Parameters, gen.parameters(op.Request, "form", "query")...)
+		}
+	}
+	for _, name := range pathParams {
+		if !documented[name] {
+			o.Parameters = append(o.Parameters, &Parameter{
+				Name: name, In: "path", Required: true, Schema: &Schema{Type: "string"},
+			})
+		}
+	}
+
+	var data *Schema
+	if t := typeOf(op.Response); t != nil {
+		data = gen.schema(t)
+	}
+	successStatus := errorx.SuccessStatusCode
+	if status := ginx.DefaultHttpStatus(); status > 0 {
+		successStatus = status
+	}
+	o.Responses[strconv.Itoa(successStatus)] = &ResponseObject{
+		Description: errorx.SuccessMsg,
+		Content:     jsonContent(responseSchema(data)),
+	}
+
+	for status, errs := range groupErrors(op.Errors) {
+		key := strconv.Itoa(status)
+		lines := make([]string, 0, len(errs))
+		codes := make([]interface{}, 0, len(errs))
+		for _, res := range errs {
+			lines = append(lines, strconv.Itoa(res.Code)+": "+res.Message)
+			codes = append(codes, res.Code)
+		}
+		desc := strings.Join(lines, "\n")
+		if exist, ok := o.Responses[key]; ok {
+			// 与成功响应同一状态码(SetDefaultHttpStatus)时合并说明
+			exist.Description += "\n" + desc
+			continue
+		}
+		schema := &Schema{
+			AllOf: []*Schema{
+				{Ref: "#/components/schemas/Response"},
+				{Type: "object", Properties: map[string]*Schema{"code": {Type: "integer", Enum: codes}}},
+			},
+		}
+		o.Responses[key] = &ResponseObject{Description: desc, Content: jsonContent(schema)}
+	}
+	return o
+}
+
+func groupErrors(errs []error) map[int][]*errorx.Response {
+	groups := make(map[int][]*errorx.Response)
+	for _, err := range errs {
+		res := errorx.UnWrapResponse(err)
+		if res == nil {
+			continue
+		}
+		status := res.StatusCode
+		if s := ginx.DefaultHttpStatus(); s > 0 {
+			status = s
+		}
+		groups[status] = append(groups[status], res)
+	}
+	for _, g := range groups {
+		sort.Slice(g, func(i, j int) bool { return g[i].Code < g[j].Code })
+	}
+	return groups
+}
+
+// responseSchema ginx.Response的结构, data为nil时不限制data类型
+func responseSchema(data *Schema) *Schema {
+	if data == nil {
+		data = &Schema{}
+	}
+	return &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"code":        {Type: "integer"},
+			ginx.MsgKey(): {Type: "string"},
+			"data":        data,
+		},
+	}
+}
+
+func jsonContent(schema *Schema) map[string]MediaType {
+	return map[string]MediaType{"application/json": {Schema: schema}}
+}
+
+// convertPath /user/:id/*path => /user/{id}/{path}
+func convertPath(path string) (string, []string) {
+	parts := strings.Split(path, "/")
+	var params []string
+	for i, part := range parts {
+		if strings.HasPrefix(part, ":") || strings.HasPrefix(part, "*") {
+			name := part[1:]
+			params = append(params, name)
+			parts[i] = "{" + name + "}"
+		}
+	}
+	return strings.Join(parts, "/"), params
+}
+
+func operationId(method, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, part := range strings.FieldsFunc(path, func(r rune) bool {
+		return r == '/' || r == ':' || r == '*' || r == '-' || r == '.' || r == '_'
+	}) {
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
+
+func bodyMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodDelete, http.MethodOptions:
+		return false
+	}
+	return true
+}
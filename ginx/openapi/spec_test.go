@@ -0,0 +1,71 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/ytf606/golibs/errorx"
+)
+
+type userReq struct {
+	Id   int64  `uri:"id" binding:"required" label:"用户id"`
+	Name string `json:"name" binding:"required,min=2,max=20" label:"用户名"`
+	Role string `json:"role" binding:"omitempty,oneof=admin guest"`
+}
+
+type userResp struct {
+	Id   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestSpec(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	spec := NewSpec("demo", "1.0")
+	api := r.Group("/api")
+	spec.Handle(api, http.MethodPut, "/user/:id", func(c *gin.Context, req *userReq) (*userResp, error) {
+		if req.Name == "root" {
+			return nil, errorx.ErrJwtTokenInvalid
+		}
+		return &userResp{Id: req.Id, Name: req.Name}, nil
+	}, Operation{Summary: "update user", Errors: []error{errorx.ErrJwtTokenInvalid}})
+	r.GET("/ping", func(c *gin.Context) {})
+	spec.Serve(r, "/openapi.json")
+
+	do := func(method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do(http.MethodPut, "/api/user/7", `{"name":"tom"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"id":7`)
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodPut, "/api/user/7", `{"name":"t"}`).Code)
+	assert.Equal(t, http.StatusUnauthorized, do(http.MethodPut, "/api/user/7", `{"name":"root"}`).Code)
+
+	w = do(http.MethodGet, "/openapi.json", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var doc Document
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &doc))
+	op := doc.Paths["/api/user/{id}"]["put"]
+	assert.NotNil(t, op)
+	assert.Equal(t, "update user", op.Summary)
+	assert.Equal(t, "id", op.Parameters[0].Name)
+	assert.Equal(t, "path", op.Parameters[0].In)
+	body := op.RequestBody.Content["application/json"].Schema
+	assert.Equal(t, []string{"name"}, body.Required)
+	assert.Equal(t, 2, *body.Properties["name"].MinLength)
+	assert.Equal(t, "用户名", body.Properties["name"].Description)
+	assert.Equal(t, []interface{}{"admin", "guest"}, body.Properties["role"].Enum)
+	assert.NotNil(t, op.Responses["401"])
+	assert.Contains(t, doc.Components.Schemas, "openapi.userResp")
+	assert.NotNil(t, doc.Paths["/ping"]["get"])
+}
@@ -1,6 +1,7 @@
 package ginx
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -8,6 +9,7 @@ import (
 	"github.com/ytf606/golibs/errorx"
 	"github.com/ytf606/golibs/ginx/validate"
 	"github.com/ytf606/golibs/logx"
+	"github.com/ytf606/golibs/logx/logtrace"
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
 )
@@ -36,7 +38,7 @@ func ToResponse(r *errorx.Response) *Response {
 
 func ParseJson(c *gin.Context, obj interface{}, code int) error {
 	if err := c.ShouldBindJSON(obj); err != nil {
-		return ErrValidate(err, code)
+		return ErrValidateDetail(err, obj, code, Locales(c)...)
 	}
 	return nil
 }
@@ -51,7 +53,7 @@ func ParseCheckJson(c *gin.Context, obj interface{}, code int) error {
 
 func ParseQuery(c *gin.Context, obj interface{}, code int) error {
 	if err := c.ShouldBindQuery(obj); err != nil {
-		return ErrValidate(err, code)
+		return ErrValidateDetail(err, obj, code, Locales(c)...)
 	}
 	return nil
 }
@@ -66,7 +68,7 @@ func ParseCheckQuery(c *gin.Context, obj interface{}, code int) error {
 
 func ParseForm(c *gin.Context, obj interface{}, code int) error {
 	if err := c.ShouldBindWith(obj, binding.Form); err != nil {
-		return ErrValidate(err, code)
+		return ErrValidateDetail(err, obj, code, Locales(c)...)
 	}
 	return nil
 }
@@ -79,19 +81,36 @@ func ParseCheckForm(c *gin.Context, obj interface{}, code int) error {
 	return nil
 }
 
-func ErrValidate(err error, code int) error {
-	t, ok := err.(validate.ValidationErrors)
-	if ok {
-		var errs validate.ValidErrors
-		for key, value := range t.Translate(validate.GetTrans()) {
-			errs = append(errs, &validate.ValidError{
-				Key: key,
-				Msg: value,
-			})
+// ErrValidate 校验错误转为400响应, locales为提示语言, 通常传Locales(c)
+// SetValidateDetail后字段级错误列表放入响应data
+func ErrValidate(err error, code int, locales ...string) error {
+	if t, ok := err.(validate.ValidationErrors); ok {
+		err = validate.ToError(t, locales...)
+	}
+	res := errorx.Wrap400Response(err, code, fmt.Sprintf("parse param error: %s", err.Error()))
+	if errs, ok := err.(validate.ValidErrors); ok && validateDetail {
+		errorx.UnWrapResponse(res).Data = errs
+	}
+	return res
+}
+
+// ErrValidateDetail 同ErrValidate, 字段路径按obj的json tag生成
+func ErrValidateDetail(err error, obj interface{}, code int, locales ...string) error {
+	if t, ok := err.(validate.ValidationErrors); ok {
+		err = validate.ToDetail(t, obj, locales...)
+	}
+	return ErrValidate(err, code, locales...)
+}
+
+// Locales 请求期望的语言, query参数(见SetLangQuery)优先, 其次Accept-Language
+func Locales(c *gin.Context) []string {
+	var locales []string
+	if langQuery != "" {
+		if lang := c.Query(langQuery); lang != "" {
+			locales = append(locales, lang)
 		}
-		err = errs
 	}
-	return errorx.Wrap400Response(err, code, fmt.Sprintf("parse param error: %s", err.Error()))
+	return append(locales, validate.ParseAcceptLanguage(c.GetHeader("Accept-Language"))...)
 }
 
 func ErrResponse(c *gin.Context, err error, status ...int) {
@@ -105,12 +124,25 @@ func ErrResponse(c *gin.Context, err error, status ...int) {
 		}
 	}
 
+	// 请求已超过deadline时, 下游因取消导致的服务端错误统一返回504
+	if c.Request != nil && c.Request.Context().Err() == context.DeadlineExceeded && res.StatusCode >= 500 {
+		timeout := errorx.UnWrapResponse(errorx.ErrRequestTimeout)
+		res = &errorx.Response{
+			StatusCode: timeout.StatusCode,
+			Code:       timeout.Code,
+			Message:    timeout.Message,
+			Err:        res,
+		}
+	}
+
 	if len(status) > 0 {
 		res.StatusCode = status[0]
 	} else if defaultHttpStatus > 0 {
 		res.StatusCode = defaultHttpStatus
 	}
 
+	logtrace.SpanFromContext(c).SetError(res)
+
 	if err := res.Err; err != nil {
 		if res.Message == "" {
 			res.Message = err.Error()
@@ -0,0 +1,291 @@
+package ginx
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ytf606/golibs/errorx"
+)
+
+// SSEContentType Logger识别该类型后不再缓存响应body
+const SSEContentType = "text/event-stream"
+
+// SSEEvent 一条SSE消息, Data为string/[]byte时原样输出, 其他类型按json编码
+type SSEEvent struct {
+	Id    string
+	Event string
+	Data  interface{}
+	// Retry 客户端重连间隔提示, 为0时不输出
+	Retry time.Duration
+}
+
+// SSEReplayBuffer 保存最近的事件, 客户端携带Last-Event-ID重连时补发
+type SSEReplayBuffer interface {
+	// Append 保存事件, Id为空时由buffer分配, 返回带Id的事件
+	Append(stream string, event SSEEvent) SSEEvent
+	// Since lastId之后的事件, lastId已被淘汰或不存在时ok为false
+	Since(stream, lastId string) (events []SSEEvent, ok bool)
+}
+
+// SSEConfig -
+type SSEConfig struct {
+	// Stream 回放用的流标识, 如任务id
+	Stream string
+	// Replay 不为nil时支持Last-Event-ID续传
+	Replay SSEReplayBuffer
+	// Retry 连接建立时下发的重连间隔
+	Retry time.Duration
+	// KeepAlive 发送注释行保持连接的间隔, 默认15s, 小于0时不发送
+	KeepAlive time.Duration
+}
+
+// SSEWriter SSE输出, 方法可并发调用, handler返回前需调用Close
+type SSEWriter struct {
+	c      *gin.Context
+	config SSEConfig
+	lock   sync.Mutex
+	seq    int64
+	closed bool
+	done   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewSSEWriter 写出SSE响应头, 并按Last-Event-ID补发事件
+//
+//	sse := ginx.NewSSEWriter(c, ginx.SSEConfig{Stream: jobId, Replay: replay})
+//	defer sse.Close()
+//	for {
+//		select {
+//		case <-sse.Done():
+//			return
+//		case p := <-progress:
+//			sse.Send(ginx.SSEEvent{Event: "progress", Data: p})
+//		}
+//	}
+func NewSSEWriter(c *gin.Context, config SSEConfig) *SSEWriter {
+	if config.KeepAlive == 0 {
+		config.KeepAlive = 15 * time.Second
+	}
+	w := &SSEWriter{c: c, config: config, done: make(chan struct{})}
+
+	header := c.Writer.Header()
+	header.Set("Content-Type", SSEContentType)
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	header.Set("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	lastId := c.GetHeader("Last-Event-ID")
+	if seq, err := strconv.ParseInt(lastId, 10, 64); err == nil {
+		w.seq = seq
+	}
+
+	w.lock.Lock()
+	if config.Retry > 0 {
+		fmt.Fprintf(c.Writer, "retry: %d\n\n", config.Retry.Milliseconds())
+	}
+	if lastId != "" && config.Replay != nil {
+		if events, ok := config.Replay.Since(config.Stream, lastId); ok {
+			for _, event := range events {
+				w.write(event)
+			}
+		}
+	}
+	c.Writer.Flush()
+	w.lock.Unlock()
+
+	w.wg.Add(1)
+	go w.watch()
+	if config.KeepAlive > 0 {
+		w.wg.Add(1)
+		go w.keepAlive()
+	}
+	return w
+}
+
+// Done 客户端断开或Close后关闭
+func (w *SSEWriter) Done() <-chan struct{} {
+	return w.done
+}
+
+// Send 发送事件, 配置了Replay时先写入回放buffer
+// Id、Event中含有换行时返回errorx.ErrSSEField, 否则可以注入任意字段; Data中的换行拆分为多行data
+func (w *SSEWriter) Send(event SSEEvent) error {
+	if strings.ContainsAny(event.Id, "\r\n") || strings.ContainsAny(event.Event, "\r\n") {
+		return errorx.ErrSSEField
+	}
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.closed || w.c.Request.Context().Err() != nil {
+		return errorx.ErrSSEClosed
+	}
+	if w.config.Replay != nil {
+		event = w.config.Replay.Append(w.config.Stream, event)
+	} else if event.Id == "" {
+		w.seq++
+		event.Id = strconv.FormatInt(w.seq, 10)
+	}
+	if err := w.write(event); err != nil {
+		return err
+	}
+	w.c.Writer.Flush()
+	return nil
+}
+
+// SendData 发送只有data的事件
+func (w *SSEWriter) SendData(data interface{}) error {
+	return w.Send(SSEEvent{Data: data})
+}
+
+// Comment 发送注释行, 客户端会忽略, text中不能含有换行
+func (w *SSEWriter) Comment(text string) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.closed || w.c.Request.Context().Err() != nil {
+		return errorx.ErrSSEClosed
+	}
+	if strings.ContainsAny(text, "\r\n") {
+		return errorx.ErrSSEField
+	}
+	if _, err := fmt.Fprintf(w.c.Writer, ": %s\n\n", text); err != nil {
+		return err
+	}
+	w.c.Writer.Flush()
+	return nil
+}
+
+// Close 停止keepalive, 之后的Send返回errorx.ErrSSEClosed
+func (w *SSEWriter) Close() {
+	w.lock.Lock()
+	if w.closed {
+		w.lock.Unlock()
+		return
+	}
+	w.closed = true
+	close(w.done)
+	w.lock.Unlock()
+	w.wg.Wait()
+}
+
+// watch 客户端断开时关闭Done, 与KeepAlive无关
+func (w *SSEWriter) watch() {
+	defer w.wg.Done()
+	select {
+	case <-w.c.Request.Context().Done():
+		w.lock.Lock()
+		if !w.closed {
+			w.closed = true
+			close(w.done)
+		}
+		w.lock.Unlock()
+	case <-w.done:
+	}
+}
+
+func (w *SSEWriter) keepAlive() {
+	defer w.wg.Done()
+	ticker := time.NewTicker(w.config.KeepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			w.Comment("keepalive")
+		case <-w.done:
+			return
+		}
+	}
+}
+
+func (w *SSEWriter) write(event SSEEvent) error {
+	var data string
+	switch v := event.Data.(type) {
+	case nil:
+	case string:
+		data = v
+	case []byte:
+		data = string(v)
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		data = string(b)
+	}
+
+	var b strings.Builder
+	if event.Id != "" {
+		b.WriteString("id: " + event.Id + "\n")
+	}
+	if event.Event != "" {
+		b.WriteString("event: " + event.Event + "\n")
+	}
+	if event.Retry > 0 {
+		b.WriteString("retry: " + strconv.FormatInt(event.Retry.Milliseconds(), 10) + "\n")
+	}
+	data = strings.Replace(strings.Replace(data, "\r\n", "\n", -1), "\r", "\n", -1)
+	for _, line := range strings.Split(data, "\n") {
+		b.WriteString("data: " + line + "\n")
+	}
+	b.WriteString("\n")
+	_, err := w.c.Writer.WriteString(b.String())
+	return err
+}
+
+type memoryReplayBuffer struct {
+	size    int
+	lock    sync.Mutex
+	streams map[string]*replayStream
+}
+
+type replayStream struct {
+	seq    int64
+	events []SSEEvent
+}
+
+// NewMemoryReplayBuffer 每个stream保留最近size条事件, 事件id为递增数字
+func NewMemoryReplayBuffer(size int) SSEReplayBuffer {
+	if size <= 0 {
+		size = 100
+	}
+	return &memoryReplayBuffer{size: size, streams: make(map[string]*replayStream)}
+}
+
+func (b *memoryReplayBuffer) Append(stream string, event SSEEvent) SSEEvent {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	s, ok := b.streams[stream]
+	if !ok {
+		s = &replayStream{}
+		b.streams[stream] = s
+	}
+	s.seq++
+	if event.Id == "" {
+		event.Id = strconv.FormatInt(s.seq, 10)
+	}
+	s.events = append(s.events, event)
+	if len(s.events) > b.size {
+		s.events = s.events[len(s.events)-b.size:]
+	}
+	return event
+}
+
+func (b *memoryReplayBuffer) Since(stream, lastId string) ([]SSEEvent, bool) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	s, ok := b.streams[stream]
+	if !ok {
+		return nil, false
+	}
+	for i, event := range s.events {
+		if event.Id == lastId {
+			return append([]SSEEvent(nil), s.events[i+1:]...), true
+		}
+	}
+	return nil, false
+}
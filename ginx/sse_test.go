@@ -0,0 +1,81 @@
+package ginx
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/ytf606/golibs/errorx"
+)
+
+func TestSSEWriter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	replay := NewMemoryReplayBuffer(10)
+	r := gin.New()
+	r.GET("/events", func(c *gin.Context) {
+		sse := NewSSEWriter(c, SSEConfig{Stream: "job", Replay: replay, KeepAlive: -1})
+		defer sse.Close()
+		sse.Send(SSEEvent{Event: "progress", Data: map[string]int{"done": 1}})
+		sse.SendData("line1\nline2")
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/events", nil))
+	assert.Equal(t, SSEContentType, w.Header().Get("Content-Type"))
+	assert.Equal(t, "id: 1\nevent: progress\ndata: {\"done\":1}\n\nid: 2\ndata: line1\ndata: line2\n\n", w.Body.String())
+
+	req := httptest.NewRequest(http.MethodGet, "/events", nil)
+	req.Header.Set("Last-Event-ID", "1")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, "id: 2\ndata: line1\ndata: line2\n\nid: 3\nevent: progress\ndata: {\"done\":1}\n\nid: 4\ndata: line1\ndata: line2\n\n", w.Body.String())
+}
+
+func TestSSEWriterInjection(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	errs := make([]error, 0, 4)
+	r := gin.New()
+	r.GET("/events", func(c *gin.Context) {
+		sse := NewSSEWriter(c, SSEConfig{KeepAlive: -1})
+		defer sse.Close()
+		errs = append(errs,
+			sse.Send(SSEEvent{Id: "1\ndata: evil", Data: "x"}),
+			sse.Send(SSEEvent{Event: "tick\revent: evil", Data: "x"}),
+			sse.Comment("ok\ndata: evil"),
+			sse.SendData("a\rb"),
+		)
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/events", nil))
+	assert.Equal(t, []error{errorx.ErrSSEField, errorx.ErrSSEField, errorx.ErrSSEField, nil}, errs)
+	// 被拒绝的事件不占用id, 单独的\r同样拆分为多行data
+	assert.Equal(t, "id: 1\ndata: a\ndata: b\n\n", w.Body.String())
+}
+
+func TestSSEWriterClientGone(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx, cancel := context.WithCancel(context.Background())
+	result := make(chan error, 1)
+	r := gin.New()
+	r.GET("/events", func(c *gin.Context) {
+		sse := NewSSEWriter(c, SSEConfig{KeepAlive: -1})
+		defer sse.Close()
+		cancel()
+		select {
+		case <-sse.Done():
+			result <- sse.SendData("late")
+		case <-time.After(time.Second):
+			result <- errors.New("Done not closed after client gone")
+		}
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/events", nil).WithContext(ctx)
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Equal(t, errorx.ErrSSEClosed, <-result)
+}
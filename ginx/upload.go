@@ -0,0 +1,268 @@
+package ginx
+
+import (
+	"bufio"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	uuid "github.com/satori/go.uuid"
+	"github.com/ytf606/golibs/errorx"
+	"github.com/ytf606/golibs/logx"
+)
+
+// UploadStorage 上传文件的存储, Save需完整读取r
+type UploadStorage interface {
+	Save(ctx context.Context, file *UploadFile, r io.Reader) (path string, err error)
+	Remove(ctx context.Context, path string) error
+}
+
+// UploadConfig 上传限制, 零值字段使用默认值
+type UploadConfig struct {
+	// MaxFileSize 单个文件最大字节数, 默认10MB
+	MaxFileSize int64
+	// MaxTotalSize 所有文件合计最大字节数, 默认32MB
+	MaxTotalSize int64
+	// MaxFiles 最多文件数, 默认10
+	MaxFiles int
+	// MaxValueSize 普通表单字段合计最大字节数, 默认1MB
+	MaxValueSize int64
+	// AllowTypes 按文件内容识别的MIME前缀白名单, 如image/、application/pdf, 为空时不限制
+	AllowTypes []string
+	// Storage 默认保存到系统临时目录
+	Storage UploadStorage
+}
+
+// UploadFile 已保存的上传文件
+type UploadFile struct {
+	Field    string `json:"field"`
+	Filename string `json:"filename"`
+	// ContentType 根据文件内容识别, 不信任客户端声明的类型
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	Md5         string `json:"md5"`
+	Sha256      string `json:"sha256"`
+	Path        string `json:"path"`
+}
+
+// MultipartForm ParseMultipart的结果
+type MultipartForm struct {
+	Values map[string][]string
+	Files  []*UploadFile
+}
+
+// File 字段的第一个文件
+func (f *MultipartForm) File(field string) *UploadFile {
+	for _, file := range f.Files {
+		if file.Field == field {
+			return file
+		}
+	}
+	return nil
+}
+
+// IsStreamBody 上传等流式请求体, 日志中间件不读取这类body
+func IsStreamBody(r *http.Request) bool {
+	ct := r.Header.Get("Content-Type")
+	return strings.HasPrefix(ct, "multipart/") || strings.HasPrefix(ct, "application/octet-stream")
+}
+
+// ParseMultipart 流式解析multipart请求, 文件边读边写入Storage并计算md5/sha256
+// 超过限制或类型不允许时删除已保存的文件并返回errorx错误, 可直接交给ErrResponse
+func ParseMultipart(c *gin.Context, config UploadConfig) (*MultipartForm, error) {
+	tag := "[ginx_ParseMultipart]"
+	if config.MaxFileSize <= 0 {
+		config.MaxFileSize = 10 << 20
+	}
+	if config.MaxTotalSize <= 0 {
+		config.MaxTotalSize = 32 << 20
+	}
+	if config.MaxFiles <= 0 {
+		config.MaxFiles = 10
+	}
+	if config.MaxValueSize <= 0 {
+		config.MaxValueSize = 1 << 20
+	}
+	if config.Storage == nil {
+		config.Storage = NewLocalStorage(os.TempDir())
+	}
+	ctx := StdCtx(c)
+
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		return nil, errorx.Wrap400Response(err, errorx.GinxUploadMalformedErr, "malformed multipart request")
+	}
+
+	form := &MultipartForm{Values: make(map[string][]string)}
+	fail := func(err error) (*MultipartForm, error) {
+		for _, f := range form.Files {
+			if rerr := config.Storage.Remove(ctx, f.Path); rerr != nil {
+				logx.Wx(ctx, tag, "remove upload file failed err:%+v, path:%s", rerr, f.Path)
+			}
+		}
+		return nil, err
+	}
+
+	var total, valueSize int64
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fail(errorx.Wrap400Response(err, errorx.GinxUploadMalformedErr, "malformed multipart request"))
+		}
+
+		field := part.FormName()
+		if part.FileName() == "" {
+			value, err := ioutil.ReadAll(io.LimitReader(part, config.MaxValueSize-valueSize+1))
+			part.Close()
+			if err != nil {
+				return fail(errorx.Wrap400Response(err, errorx.GinxUploadMalformedErr, "malformed multipart request"))
+			}
+			valueSize += int64(len(value))
+			if valueSize > config.MaxValueSize {
+				return fail(errorx.ErrUploadTooLarge)
+			}
+			form.Values[field] = append(form.Values[field], string(value))
+			continue
+		}
+
+		if len(form.Files) >= config.MaxFiles {
+			part.Close()
+			return fail(errorx.ErrUploadTooLarge)
+		}
+		file, err := saveUploadPart(ctx, config, part, total)
+		part.Close()
+		if err != nil {
+			return fail(err)
+		}
+		total += file.Size
+		form.Files = append(form.Files, file)
+	}
+	return form, nil
+}
+
+type uploadPart interface {
+	io.Reader
+	FormName() string
+	FileName() string
+}
+
+func saveUploadPart(ctx context.Context, config UploadConfig, part uploadPart, total int64) (*UploadFile, error) {
+	br := bufio.NewReaderSize(part, 512)
+	head, _ := br.Peek(512)
+	contentType := http.DetectContentType(head)
+	if !allowUploadType(config.AllowTypes, contentType) {
+		return nil, errorx.ErrUploadType
+	}
+
+	file := &UploadFile{
+		Field:       part.FormName(),
+		Filename:    filepath.Base(part.FileName()),
+		ContentType: contentType,
+	}
+	limit := config.MaxFileSize
+	if left := config.MaxTotalSize - total; left < limit {
+		limit = left
+	}
+	md5Hash, shaHash := md5.New(), sha256.New()
+	counter := &limitCounter{r: br, limit: limit}
+	path, err := config.Storage.Save(ctx, file, io.TeeReader(counter, io.MultiWriter(md5Hash, shaHash)))
+	if counter.exceeded {
+		if path != "" {
+			config.Storage.Remove(ctx, path)
+		}
+		return nil, errorx.ErrUploadTooLarge
+	}
+	if err != nil {
+		return nil, errorx.Wrap500Response(err, errorx.GinxUploadStorageErr, "save upload file failed")
+	}
+	file.Path = path
+	file.Size = counter.n
+	file.Md5 = hex.EncodeToString(md5Hash.Sum(nil))
+	file.Sha256 = hex.EncodeToString(shaHash.Sum(nil))
+	return file, nil
+}
+
+func allowUploadType(allows []string, contentType string) bool {
+	if len(allows) == 0 {
+		return true
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	for _, t := range allows {
+		if strings.HasPrefix(mediaType, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// limitCounter 超过limit时返回错误, 让Storage提前结束写入
+type limitCounter struct {
+	r        io.Reader
+	limit    int64
+	n        int64
+	exceeded bool
+}
+
+func (l *limitCounter) Read(p []byte) (int, error) {
+	if l.n >= l.limit {
+		// 多读1字节判断是否真的超限
+		var one [1]byte
+		if n, _ := l.r.Read(one[:]); n > 0 {
+			l.exceeded = true
+			return 0, errorx.ErrUploadTooLarge
+		}
+		return 0, io.EOF
+	}
+	if left := l.limit - l.n; int64(len(p)) > left {
+		p = p[:left]
+	}
+	n, err := l.r.Read(p)
+	l.n += int64(n)
+	return n, err
+}
+
+type localStorage struct {
+	dir string
+}
+
+// NewLocalStorage 保存到本地目录, 文件名为随机id加原扩展名
+func NewLocalStorage(dir string) UploadStorage {
+	return &localStorage{dir: dir}
+}
+
+func (s *localStorage) Save(ctx context.Context, file *UploadFile, r io.Reader) (string, error) {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return "", err
+	}
+	name := strings.Replace(uuid.NewV4().String(), "-", "", -1) + strings.ToLower(filepath.Ext(file.Filename))
+	path := filepath.Join(s.dir, name)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return "", err
+	}
+	_, err = io.Copy(f, r)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path)
+		return "", err
+	}
+	return path, nil
+}
+
+func (s *localStorage) Remove(ctx context.Context, path string) error {
+	return os.Remove(path)
+}
@@ -0,0 +1,68 @@
+package ginx
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMultipart(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dir := t.TempDir()
+	config := UploadConfig{MaxFileSize: 16, AllowTypes: []string{"text/"}, Storage: NewLocalStorage(dir)}
+	var form *MultipartForm
+	r := gin.New()
+	r.POST("/upload", func(c *gin.Context) {
+		var err error
+		if form, err = ParseMultipart(c, config); err != nil {
+			ErrResponse(c, err)
+			return
+		}
+		SuccResponse(c, form.Files)
+	})
+
+	upload := func(content []byte) *httptest.ResponseRecorder {
+		body := &bytes.Buffer{}
+		mw := multipart.NewWriter(body)
+		mw.WriteField("name", "avatar")
+		fw, _ := mw.CreateFormFile("file", "a.txt")
+		fw.Write(content)
+		mw.Close()
+		req := httptest.NewRequest(http.MethodPost, "/upload", body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	content := []byte("hello world")
+	w := upload(content)
+	assert.Equal(t, http.StatusOK, w.Code)
+	file := form.File("file")
+	if assert.NotNil(t, file) {
+		sum := md5.Sum(content)
+		assert.Equal(t, hex.EncodeToString(sum[:]), file.Md5)
+		assert.Equal(t, int64(len(content)), file.Size)
+		assert.Equal(t, "a.txt", file.Filename)
+		data, _ := os.ReadFile(file.Path)
+		assert.Equal(t, content, data)
+	}
+	assert.Equal(t, []string{"avatar"}, form.Values["name"])
+
+	w = upload(bytes.Repeat([]byte("a"), 17))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+
+	w = upload([]byte("\x89PNG\r\n\x1a\n"))
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+
+	entries, _ := os.ReadDir(dir)
+	assert.Len(t, entries, 1)
+}
@@ -1,40 +1,114 @@
 package validate
 
 import (
+	"reflect"
 	"regexp"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-playground/validator/v10"
 )
 
 type customValidate struct {
-	fn         func(validator.FieldLevel) bool
-	translator string
+	// fn 为空时只注册提示, 用于结构体级校验报告的tag
+	fn func(validator.FieldLevel) bool
+	// translations 各语言的提示模板, {0}为字段名
+	translations map[TransLang]string
+	// fieldParam 参数为同级字段名, 提示中的{1}显示该字段的label或json名
+	fieldParam bool
 }
 
 //定义映射关系
 var customMap = map[string]customValidate{
 	"after_date": {
-		fn:         afterDate,
-		translator: "{0}必须要晚于当前日期",
+		fn: afterDate,
+		translations: map[TransLang]string{
+			ZH: "{0}必须要晚于当前日期",
+			EN: "{0} must be later than the current date",
+		},
 	},
 	"before_date": {
-		fn:         beforeDate,
-		translator: "{0}必须要早于当前日期",
+		fn: beforeDate,
+		translations: map[TransLang]string{
+			ZH: "{0}必须要早于当前日期",
+			EN: "{0} must be earlier than the current date",
+		},
 	},
 	"check_mobile": {
-		fn:         checkMobile,
-		translator: "{0}必须是一个有效的手机号码",
+		fn: checkMobile,
+		translations: map[TransLang]string{
+			ZH: "{0}必须是一个有效的手机号码",
+			EN: "{0} must be a valid mobile number",
+		},
 	},
 	"check_login": {
-		fn:         checkLogin,
-		translator: "{0}必须由数字、字母或特殊字符组成",
+		fn: checkLogin,
+		translations: map[TransLang]string{
+			ZH: "{0}必须由数字、字母或特殊字符组成",
+			EN: "{0} must consist of digits, letters or special characters",
+		},
+	},
+	"id_card": {
+		fn: checkIdCard,
+		translations: map[TransLang]string{
+			ZH: "{0}必须是一个有效的身份证号码",
+			EN: "{0} must be a valid ID card number",
+		},
+	},
+	"credit_code": {
+		fn: checkCreditCode,
+		translations: map[TransLang]string{
+			ZH: "{0}必须是一个有效的统一社会信用代码",
+			EN: "{0} must be a valid unified social credit code",
+		},
+	},
+	"intl_phone": {
+		fn: checkIntlPhone,
+		translations: map[TransLang]string{
+			ZH: "{0}必须是一个有效的国际电话号码",
+			EN: "{0} must be a valid international phone number",
+		},
+	},
+	"after_field": {
+		fn:         afterField,
+		fieldParam: true,
+		translations: map[TransLang]string{
+			ZH: "{0}必须晚于{1}",
+			EN: "{0} must be later than {1}",
+		},
+	},
+	"before_field": {
+		fn:         beforeField,
+		fieldParam: true,
+		translations: map[TransLang]string{
+			ZH: "{0}必须早于{1}",
+			EN: "{0} must be earlier than {1}",
+		},
 	},
 }
 
+var (
+	intlPhoneReg = regexp.MustCompile(`^\+[1-9]\d{6,14}$`)
+
+	idCardWeights = []int{7, 9, 10, 5, 8, 4, 2, 1, 6, 3, 7, 9, 10, 5, 8, 4, 2}
+	idCardChecks  = "10X98765432"
+
+	creditCodeChars   = "0123456789ABCDEFGHJKLMNPQRTUWXY"
+	creditCodeWeights = []int{1, 3, 9, 27, 19, 26, 16, 17, 20, 29, 25, 13, 8, 24, 10, 30, 28}
+
+	dateLayouts = []string{"2006-01-02", "2006-01-02 15:04:05", time.RFC3339}
+
+	// locations 按时区名缓存time.LoadLocation的结果, 避免每次校验都读取时区文件
+	locations sync.Map
+	// rootTypes 跨字段规则所在的顶层结构体, key为reflect.Type, 翻译时据此找到参数字段的label或json名
+	rootTypes sync.Map
+)
+
+// afterDate 参数为时区, 如after_date=Asia/Shanghai, 不写时按UTC解析日期, after_date=Local按服务器本地时区
 func afterDate(fl validator.FieldLevel) bool {
-	date, err := time.Parse("2006-01-02", fl.Field().String())
-	if err != nil {
+	date, ok := fieldDate(fl.Field(), fl.Param())
+	if !ok {
 		return false
 	}
 	if date.Before(time.Now()) {
@@ -44,8 +118,8 @@ func afterDate(fl validator.FieldLevel) bool {
 }
 
 func beforeDate(fl validator.FieldLevel) bool {
-	date, err := time.Parse("2006-01-02", fl.Field().String())
-	if err != nil {
+	date, ok := fieldDate(fl.Field(), fl.Param())
+	if !ok {
 		return false
 	}
 	if date.After(time.Now()) {
@@ -54,6 +128,147 @@ func beforeDate(fl validator.FieldLevel) bool {
 	return true
 }
 
+// afterField 跨字段比较日期, 如EndDate `binding:"after_field=StartDate"`
+func afterField(fl validator.FieldLevel) bool {
+	return compareField(fl, func(cur, other time.Time) bool { return cur.After(other) })
+}
+
+func beforeField(fl validator.FieldLevel) bool {
+	return compareField(fl, func(cur, other time.Time) bool { return cur.Before(other) })
+}
+
+func compareField(fl validator.FieldLevel, cmp func(cur, other time.Time) bool) bool {
+	if top := indirect(fl.Top().Type()); top != nil && top.Kind() == reflect.Struct {
+		if _, ok := rootTypes.Load(top); !ok {
+			rootTypes.Store(top, struct{}{})
+		}
+	}
+	field, _, _, ok := fl.GetStructFieldOK2()
+	if !ok {
+		return false
+	}
+	cur, ok := fieldDate(fl.Field(), "")
+	if !ok {
+		return false
+	}
+	other, ok := fieldDate(field, "")
+	return ok && cmp(cur, other)
+}
+
+// fieldDate 支持time.Time和日期字符串, 不带时区的字符串按zone解析
+func fieldDate(field reflect.Value, zone string) (time.Time, bool) {
+	for field.Kind() == reflect.Ptr {
+		if field.IsNil() {
+			return time.Time{}, false
+		}
+		field = field.Elem()
+	}
+	if t, ok := field.Interface().(time.Time); ok {
+		return t, !t.IsZero()
+	}
+	if field.Kind() != reflect.String {
+		return time.Time{}, false
+	}
+	loc := time.UTC
+	if zone != "" {
+		var err error
+		if loc, err = loadLocation(zone); err != nil {
+			return time.Time{}, false
+		}
+	}
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, field.String(), loc); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// loadLocation 同一时区只加载一次, 加载失败的结果同样缓存
+func loadLocation(zone string) (*time.Location, error) {
+	if v, ok := locations.Load(zone); ok {
+		if err, ok := v.(error); ok {
+			return nil, err
+		}
+		return v.(*time.Location), nil
+	}
+	loc, err := time.LoadLocation(zone)
+	if err != nil {
+		locations.Store(zone, err)
+		return nil, err
+	}
+	locations.Store(zone, loc)
+	return loc, nil
+}
+
+// paramLabel 返回参数字段的label或json名
+// FieldError不带结构体类型, 按StructNamespace在记录的顶层结构体中查找, 同名结构体的结果不一致时返回参数原值
+func paramLabel(fe validator.FieldError) string {
+	parts := strings.Split(fe.StructNamespace(), ".")
+	label := ""
+	rootTypes.Range(func(key, _ interface{}) bool {
+		t := key.(reflect.Type)
+		if t.Name() != parts[0] {
+			return true
+		}
+		name, ok := structParamLabel(t, parts[1:], fe)
+		if !ok {
+			return true
+		}
+		if label != "" && label != name {
+			label = ""
+			return false
+		}
+		label = name
+		return true
+	})
+	if label == "" {
+		return fe.Param()
+	}
+	return label
+}
+
+// structParamLabel 沿路径找到校验失败字段所在的结构体, 该字段需带有同样的规则, 返回参数字段的名称
+func structParamLabel(t reflect.Type, parts []string, fe validator.FieldError) (string, bool) {
+	for _, part := range parts[:len(parts)-1] {
+		index := ""
+		if i := strings.IndexByte(part, '['); i >= 0 {
+			part, index = part[:i], part[i:]
+		}
+		if t = indirect(t); t == nil || t.Kind() != reflect.Struct {
+			return "", false
+		}
+		f, ok := t.FieldByName(part)
+		if !ok {
+			return "", false
+		}
+		t = f.Type
+		for n := strings.Count(index, "["); n > 0; n-- {
+			if t = indirect(t); t == nil || (t.Kind() != reflect.Slice && t.Kind() != reflect.Array && t.Kind() != reflect.Map) {
+				return "", false
+			}
+			t = t.Elem()
+		}
+	}
+	if t = indirect(t); t == nil || t.Kind() != reflect.Struct {
+		return "", false
+	}
+	cur, ok := t.FieldByName(fe.StructField())
+	rule := fe.Tag() + "=" + fe.Param()
+	if !ok || !(strings.Contains(cur.Tag.Get("binding"), rule) || strings.Contains(cur.Tag.Get("validate"), rule)) {
+		return "", false
+	}
+	f, ok := t.FieldByName(fe.Param())
+	if !ok {
+		return "", false
+	}
+	name := labelName(f)
+	if name == "" {
+		name = fe.Param()
+	}
+	return name, true
+}
+
 func checkMobile(fl validator.FieldLevel) bool {
 	match, _ := regexp.MatchString(`^(1[3-9][0-9]\d{8})$`, fl.Field().String())
 	return match
@@ -72,3 +287,53 @@ func checkLogin(fl validator.FieldLevel) bool {
 	}
 	return true
 }
+
+// checkIdCard 18位居民身份证号, 校验出生日期和校验码
+func checkIdCard(fl validator.FieldLevel) bool {
+	id := strings.ToUpper(fl.Field().String())
+	if len(id) != 18 {
+		return false
+	}
+	if _, err := time.Parse("20060102", id[6:14]); err != nil {
+		return false
+	}
+	sum := 0
+	for i, w := range idCardWeights {
+		c := id[i]
+		if c < '0' || c > '9' {
+			return false
+		}
+		sum += int(c-'0') * w
+	}
+	return id[17] == idCardChecks[sum%11]
+}
+
+// checkCreditCode 18位统一社会信用代码(GB 32100-2015), 校验字符集和校验码
+func checkCreditCode(fl validator.FieldLevel) bool {
+	code := strings.ToUpper(fl.Field().String())
+	if len(code) != 18 {
+		return false
+	}
+	sum := 0
+	for i, w := range creditCodeWeights {
+		n := strings.IndexByte(creditCodeChars, code[i])
+		if n < 0 {
+			return false
+		}
+		sum += n * w
+	}
+	check := (31 - sum%31) % 31
+	return code[17] == creditCodeChars[check]
+}
+
+// checkIntlPhone E.164格式的国际电话号码, 允许空格、-和括号分隔, 如+86 138-0013-8000
+func checkIntlPhone(fl validator.FieldLevel) bool {
+	phone := strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '(', ')':
+			return -1
+		}
+		return r
+	}, fl.Field().String())
+	return intlPhoneReg.MatchString(phone)
+}
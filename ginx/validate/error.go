@@ -1,6 +1,7 @@
 package validate
 
 import (
+	"reflect"
 	"strings"
 
 	"github.com/go-playground/validator/v10"
@@ -11,9 +12,16 @@ type (
 	FieldError       = validator.FieldError
 )
 
+// ValidError 字段校验错误, json序列化后可直接返回给客户端
 type ValidError struct {
-	Key string
-	Msg string
+	Key string `json:"-"`
+	Msg string `json:"message"`
+	// Source 字段来源, 如uri、header、query、form、json, 由ginx.Bind填充
+	Source string `json:"source,omitempty"`
+	// Path 按json tag命名的字段路径, 如items[0].name, 由ToDetail和ginx.Bind填充
+	Path  string `json:"field,omitempty"`
+	Tag   string `json:"tag,omitempty"`
+	Param string `json:"param,omitempty"`
 }
 
 type ValidErrors []*ValidError
@@ -35,7 +43,8 @@ func (v ValidErrors) Errors() []string {
 	return errs
 }
 
-func ToError(err interface{}) ValidErrors {
+// ToError 翻译校验错误, locales为请求的语言, 见GetTrans
+func ToError(err interface{}, locales ...string) ValidErrors {
 	var errs ValidErrors
 
 	verrs, ok := err.(validator.ValidationErrors)
@@ -43,12 +52,91 @@ func ToError(err interface{}) ValidErrors {
 		return errs
 	}
 
-	for key, value := range verrs.Translate(trans) {
+	trans := GetTrans(locales...)
+	for _, fe := range verrs {
 		errs = append(errs, &ValidError{
-			Key: key,
-			Msg: value,
+			Key:   fe.Namespace(),
+			Msg:   fe.Translate(trans),
+			Tag:   fe.Tag(),
+			Param: fe.Param(),
 		})
 	}
 
 	return errs
 }
+
+// ToDetail 同ToError, 并按obj的json tag填充字段路径Path
+func ToDetail(err interface{}, obj interface{}, locales ...string) ValidErrors {
+	errs := ToError(err, locales...)
+	verrs, _ := err.(validator.ValidationErrors)
+	t := reflect.TypeOf(obj)
+	for i, fe := range verrs {
+		errs[i].Path = FieldPath(t, fe.StructNamespace())
+	}
+	return errs
+}
+
+// FieldPath 将validator的StructNamespace(如Req.Items[0].Name)转为按json tag命名的路径(如items[0].name)
+// 未写json tag时依次使用form、uri、header tag和字段名, 匿名嵌入的结构体与json一样展开
+func FieldPath(t reflect.Type, namespace string) string {
+	var b strings.Builder
+	parts := strings.Split(namespace, ".")
+	for _, part := range parts[1:] {
+		name, index := part, ""
+		if i := strings.IndexByte(part, '['); i >= 0 {
+			name, index = part[:i], part[i:]
+		}
+		t = indirect(t)
+		if t != nil && t.Kind() == reflect.Struct {
+			if f, ok := t.FieldByName(name); ok {
+				t = f.Type
+				if f.Anonymous && index == "" && tagName(f, "json") == "" {
+					continue
+				}
+				name = fieldName(f)
+			} else {
+				t = nil
+			}
+		} else {
+			t = nil
+		}
+		// 每层下标取一次元素类型
+		for n := strings.Count(index, "["); n > 0 && t != nil; n-- {
+			if t = indirect(t); t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map {
+				t = t.Elem()
+			} else {
+				t = nil
+			}
+		}
+		if b.Len() > 0 {
+			b.WriteByte('.')
+		}
+		b.WriteString(name)
+		b.WriteString(index)
+	}
+	return b.String()
+}
+
+func fieldName(f reflect.StructField) string {
+	for _, tag := range []string{"json", "form", "uri", "header"} {
+		if name := tagName(f, tag); name != "" {
+			return name
+		}
+	}
+	return f.Name
+}
+
+func tagName(f reflect.StructField, tag string) string {
+	name := strings.SplitN(f.Tag.Get(tag), ",", 2)[0]
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+func indirect(t reflect.Type) reflect.Type {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
@@ -9,7 +9,12 @@ import (
 func HookRegisterValidation(obj *validator.Validate) error {
 	// 在校验器注册自定义的校验方法
 	var err error
+	customLock.RLock()
+	defer customLock.RUnlock()
 	for k, v := range customMap {
+		if v.fn == nil {
+			continue
+		}
 		if err = obj.RegisterValidation(k, v.fn); err != nil {
 			logx.E("[ginx_validate_HookRegisterValidation]", "register custom validate failed err:%+v, func:%s", err, k)
 		}
@@ -18,24 +23,20 @@ func HookRegisterValidation(obj *validator.Validate) error {
 }
 
 func HookRegisterTranslator(obj *validator.Validate) error {
-	var err error
+	customLock.RLock()
+	defer customLock.RUnlock()
 	for k, v := range customMap {
-		if err = obj.RegisterTranslation(
-			k,
-			GetTrans(),
-			registerTranslator(k, v.translator),
-			translate,
-		); err != nil {
+		if err := registerMessages(obj, k, v.translations); err != nil {
 			logx.E("[ginx_validate_HookRegisterTranslator]", "register custom translator failed err:%+v, func:%s", err, k)
 		}
 	}
 	return nil
 }
 
-// registerTranslator 为自定义字段添加翻译功能
+// registerTranslator 为自定义字段添加翻译功能, 重复注册时覆盖
 func registerTranslator(tag string, msg string) validator.RegisterTranslationsFunc {
 	return func(trans ut.Translator) error {
-		if err := trans.Add(tag, msg, false); err != nil {
+		if err := trans.Add(tag, msg, true); err != nil {
 			return err
 		}
 		return nil
@@ -44,7 +45,14 @@ func registerTranslator(tag string, msg string) validator.RegisterTranslationsFu
 
 // translate 自定义字段的翻译方法
 func translate(trans ut.Translator, fe validator.FieldError) string {
-	msg, err := trans.T(fe.Tag(), fe.Field())
+	param := fe.Param()
+	customLock.RLock()
+	fieldParam := customMap[fe.Tag()].fieldParam
+	customLock.RUnlock()
+	if fieldParam {
+		param = paramLabel(fe)
+	}
+	msg, err := trans.T(fe.Tag(), fe.Field(), param)
 	if err != nil {
 		logx.E("[ginx_validate_translate]", "custom translate failed err:%+v, tag:%s", err, fe.Tag())
 	}
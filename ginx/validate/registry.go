@@ -0,0 +1,83 @@
+package validate
+
+import (
+	"sync"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/ytf606/golibs/errorx"
+)
+
+var customLock sync.RWMutex
+
+// Register 注册自定义校验规则及各语言提示模板({0}字段名, {1}规则参数), 同名规则覆盖
+// InitTrans前后均可调用, 之前调用时提示在InitTrans中注册; 需在服务启动阶段调用, validator不支持与校验并发注册
+// 跨字段规则可在fn中通过fl.GetStructFieldOK2()取参数指定的字段, 见after_field
+func Register(tag string, fn validator.Func, translations map[TransLang]string) error {
+	if fn == nil {
+		return errorx.Errorf("validate register %s: nil func", tag)
+	}
+	customLock.Lock()
+	customMap[tag] = customValidate{fn: fn, translations: translations}
+	customLock.Unlock()
+
+	v, ok := engine()
+	if !ok {
+		return nil
+	}
+	if err := v.RegisterValidation(tag, fn); err != nil {
+		return err
+	}
+	return registerMessages(v, tag, translations)
+}
+
+// RegisterMessage 只注册提示模板, 用于结构体级校验中ReportError使用的tag
+func RegisterMessage(tag string, translations map[TransLang]string) error {
+	customLock.Lock()
+	custom := customMap[tag]
+	custom.translations = translations
+	customMap[tag] = custom
+	customLock.Unlock()
+
+	v, ok := engine()
+	if !ok {
+		return nil
+	}
+	return registerMessages(v, tag, translations)
+}
+
+// RegisterStruct 注册结构体级校验, 在fn中用sl.ReportError(field, fieldName, structFieldName, tag, param)报告错误
+//
+//	validate.RegisterStruct(func(sl validator.StructLevel) {
+//		req := sl.Current().Interface().(RegisterReq)
+//		if req.Email == "" && req.Mobile == "" {
+//			sl.ReportError(req.Mobile, "mobile", "Mobile", "email_or_mobile", "")
+//		}
+//	}, RegisterReq{})
+func RegisterStruct(fn validator.StructLevelFunc, types ...interface{}) {
+	if v, ok := engine(); ok {
+		v.RegisterStructValidation(fn, types...)
+	}
+}
+
+func engine() (*validator.Validate, bool) {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	return v, ok
+}
+
+// registerMessages InitTrans之前uni为空, 由HookRegisterTranslator统一注册
+func registerMessages(v *validator.Validate, tag string, translations map[TransLang]string) error {
+	if uni == nil {
+		return nil
+	}
+	for lang, msg := range translations {
+		t, ok := uni.GetTranslator(string(lang))
+		if !ok {
+			continue
+		}
+		if err := v.RegisterTranslation(tag, t, registerTranslator(tag, msg), translate); err != nil {
+			return err
+		}
+	}
+	return nil
+}
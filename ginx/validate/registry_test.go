@@ -0,0 +1,108 @@
+package validate
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/stretchr/testify/assert"
+)
+
+type companyReq struct {
+	IdCard     string `json:"id_card" binding:"omitempty,id_card"`
+	CreditCode string `json:"credit_code" binding:"omitempty,credit_code"`
+	Phone      string `json:"phone" binding:"omitempty,intl_phone"`
+	StartDate  string `json:"start_date" label:"开始日期"`
+	EndDate    string `json:"end_date" binding:"omitempty,after_field=StartDate"`
+	Code       string `json:"code" binding:"omitempty,upper_code"`
+}
+
+type periodReq struct {
+	From string `json:"from"`
+	To   string `json:"to" binding:"before_field=From"`
+}
+
+type scheduleReq struct {
+	Periods []periodReq `json:"periods" binding:"dive"`
+}
+
+func TestRegister(t *testing.T) {
+	// 在InitTrans之前注册的规则, 提示在InitTrans中注册; 其他测试可能已调用InitTrans, 先重置
+	uni = nil
+	assert.Nil(t, Register("upper_code", func(fl validator.FieldLevel) bool {
+		return strings.ToUpper(fl.Field().String()) == fl.Field().String()
+	}, map[TransLang]string{ZH: "{0}必须为大写", EN: "{0} must be upper case"}))
+	assert.Nil(t, InitTrans(EN))
+
+	valid := func(req companyReq) error {
+		return binding.Validator.ValidateStruct(&req)
+	}
+	assert.Nil(t, valid(companyReq{
+		IdCard:     "11010519491231002x",
+		CreditCode: "91350100M000100Y43",
+		Phone:      "+86 138-0013-8000",
+		StartDate:  "2024-01-01",
+		EndDate:    "2024-01-02",
+	}))
+	assert.NotNil(t, valid(companyReq{IdCard: "110105194912310021"}))
+	assert.NotNil(t, valid(companyReq{CreditCode: "91350100M000100Y44"}))
+	assert.NotNil(t, valid(companyReq{Phone: "13800138000"}))
+	assert.NotNil(t, valid(companyReq{StartDate: "2024-01-02", EndDate: "2024-01-01"}))
+
+	// 在InitTrans之后覆盖提示
+	assert.Nil(t, RegisterMessage("upper_code", map[TransLang]string{ZH: "{0}必须为大写", EN: "{0} must be in upper case"}))
+	assert.Equal(t, "code must be in upper case", ToError(valid(companyReq{Code: "ab"})).Error())
+	assert.Equal(t, "code必须为大写", ToError(valid(companyReq{Code: "ab"}), "zh").Error())
+	// {1}显示参数字段的label或json名
+	assert.Equal(t, "end_date must be later than 开始日期",
+		ToError(valid(companyReq{StartDate: "2024-01-02", EndDate: "2024-01-01"})).Error())
+	nested := &scheduleReq{Periods: []periodReq{{From: "2024-01-01", To: "2024-01-02"}}}
+	assert.Equal(t, "to must be earlier than from", ToError(binding.Validator.ValidateStruct(nested)).Error())
+
+	// 不同包中的同名结构体互不影响, 用函数内声明的同名类型模拟
+	userReq := func() error {
+		type Req struct {
+			Start string `json:"start" label:"join_date"`
+			End   string `json:"end" binding:"after_field=Start"`
+		}
+		return binding.Validator.ValidateStruct(&Req{Start: "2024-01-02", End: "2024-01-01"})
+	}
+	orderReq := func() error {
+		type Req struct {
+			Begin  string `json:"begin" label:"order_date"`
+			Finish string `json:"finish" binding:"after_field=Begin"`
+		}
+		return binding.Validator.ValidateStruct(&Req{Begin: "2024-01-02", Finish: "2024-01-01"})
+	}
+	userErr, orderErr := userReq(), orderReq()
+	assert.Equal(t, "end must be later than join_date", ToError(userErr).Error())
+	assert.Equal(t, "finish must be later than order_date", ToError(orderErr).Error())
+}
+
+func TestLoadLocation(t *testing.T) {
+	loc, err := loadLocation("Asia/Shanghai")
+	assert.Nil(t, err)
+	cached, _ := loadLocation("Asia/Shanghai")
+	assert.True(t, loc == cached)
+	_, err = loadLocation("Nowhere/City")
+	assert.NotNil(t, err)
+	_, err = loadLocation("Nowhere/City")
+	assert.NotNil(t, err)
+
+	// 不指定时区时按UTC解析
+	date, ok := fieldDate(reflect.ValueOf("2024-01-01"), "")
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), date)
+	date, ok = fieldDate(reflect.ValueOf("2024-01-01"), "Local")
+	assert.True(t, ok)
+	assert.Equal(t, time.Local, date.Location())
+
+	date, ok = fieldDate(reflect.ValueOf("2024-01-01"), "Asia/Shanghai")
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(2023, 12, 31, 16, 0, 0, 0, time.UTC), date.UTC())
+	_, ok = fieldDate(reflect.ValueOf("2024-01-01"), "Nowhere/City")
+	assert.False(t, ok)
+}
@@ -2,6 +2,8 @@ package validate
 
 import (
 	"reflect"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin/binding"
@@ -13,7 +15,10 @@ import (
 	zh_translations "github.com/go-playground/validator/v10/translations/zh"
 )
 
-var trans ut.Translator
+var (
+	trans ut.Translator
+	uni   *ut.UniversalTranslator
+)
 
 type TransLang string
 
@@ -22,21 +27,19 @@ const (
 	EN TransLang = "en"
 )
 
+// langs 支持的语言及其默认翻译, 自定义规则的翻译见customMap
+var langs = map[TransLang]func(v *validator.Validate, trans ut.Translator) error{
+	ZH: zh_translations.RegisterDefaultTranslations,
+	EN: en_translations.RegisterDefaultTranslations,
+}
+
+// InitTrans 注册所有支持语言的翻译, lang为请求未指定或不支持的语言时使用的默认语言
 func InitTrans(lang TransLang) (err error) {
 	// 修改gin框架中的Validator引擎属性，实现自定制
 	v, ok := binding.Validator.Engine().(*validator.Validate)
 	if ok {
 		// 注册一个获取json tag的自定义方法
-		v.RegisterTagNameFunc(func(fld reflect.StructField) string {
-			name := strings.SplitN(fld.Tag.Get("label"), ",", 2)[0]
-			if name == "" {
-				name = strings.SplitN(fld.Tag.Get("json"), ",", 2)[0]
-			}
-			if name == "-" {
-				return ""
-			}
-			return name
-		})
+		v.RegisterTagNameFunc(labelName)
 
 		HookRegisterValidation(v)
 
@@ -44,28 +47,84 @@ func InitTrans(lang TransLang) (err error) {
 		enT := en.New() // 英文翻译器
 		// 第一个参数是备用（fallback）的语言环境
 		// 后面的参数是应该支持的语言环境（支持多个）
-		// uni := ut.New(zhT, zhT) 也是可以的
-		uni := ut.New(enT, zhT, enT)
-		trans, _ = uni.GetTranslator(string(lang))
-		switch lang {
-		case ZH:
-			err = zh_translations.RegisterDefaultTranslations(v, trans)
-			break
-		case EN:
-			err = en_translations.RegisterDefaultTranslations(v, trans)
-			break
-		default:
-			err = en_translations.RegisterDefaultTranslations(v, trans)
-			break
+		uni = ut.New(enT, zhT, enT)
+		for l, register := range langs {
+			t, _ := uni.GetTranslator(string(l))
+			if err = register(v, t); err != nil {
+				return
+			}
 		}
-		if err != nil {
-			return
+		if _, ok := langs[lang]; !ok {
+			lang = EN
 		}
+		trans, _ = uni.GetTranslator(string(lang))
 		err = HookRegisterTranslator(v)
 	}
 	return
 }
 
-func GetTrans() ut.Translator {
+// labelName 校验提示中的字段名, 优先取label tag, 其次json tag
+func labelName(fld reflect.StructField) string {
+	name := strings.SplitN(fld.Tag.Get("label"), ",", 2)[0]
+	if name == "" {
+		name = strings.SplitN(fld.Tag.Get("json"), ",", 2)[0]
+	}
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+// GetTrans 按locales顺序取第一个支持的翻译器, 都不支持或未指定时返回InitTrans的默认翻译器
+// locale可以是zh、en, 也可以是zh-CN、en_US这类带地区的写法
+func GetTrans(locales ...string) ut.Translator {
+	if uni == nil || len(locales) == 0 {
+		return trans
+	}
+	for _, locale := range locales {
+		locale = strings.ToLower(strings.Replace(locale, "-", "_", -1))
+		if t, ok := uni.GetTranslator(locale); ok {
+			return t
+		}
+		if i := strings.IndexByte(locale, '_'); i > 0 {
+			if t, ok := uni.GetTranslator(locale[:i]); ok {
+				return t
+			}
+		}
+	}
 	return trans
 }
+
+// ParseAcceptLanguage 按q值从高到低返回Accept-Language中的语言
+func ParseAcceptLanguage(header string) []string {
+	type item struct {
+		lang string
+		q    float64
+	}
+	var items []item
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		lang := strings.TrimSpace(fields[0])
+		if lang == "" || lang == "*" {
+			continue
+		}
+		q := 1.0
+		for _, f := range fields[1:] {
+			f = strings.TrimSpace(f)
+			if strings.HasPrefix(f, "q=") {
+				if v, err := strconv.ParseFloat(f[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		if q > 0 {
+			items = append(items, item{lang: lang, q: q})
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].q > items[j].q })
+	locales := make([]string, 0, len(items))
+	for _, it := range items {
+		locales = append(locales, it.lang)
+	}
+	return locales
+}
@@ -0,0 +1,48 @@
+package validate
+
+import (
+	"testing"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/stretchr/testify/assert"
+)
+
+type loginReq struct {
+	Mobile string `json:"mobile" binding:"required,check_mobile"`
+}
+
+func TestTransLocale(t *testing.T) {
+	assert.Nil(t, InitTrans(ZH))
+	assert.Equal(t, []string{"en-US", "zh", "en"}, ParseAcceptLanguage("zh;q=0.8, en-US,en;q=0.5,*;q=0.1"))
+
+	err := binding.Validator.ValidateStruct(&loginReq{Mobile: "123"})
+	assert.Equal(t, "mobile必须是一个有效的手机号码", ToError(err).Error())
+	assert.Equal(t, "mobile must be a valid mobile number", ToError(err, "en-US").Error())
+	assert.Equal(t, "mobile必须是一个有效的手机号码", ToError(err, "fr", "zh_CN").Error())
+
+	err = binding.Validator.ValidateStruct(&loginReq{})
+	assert.Equal(t, "mobile is a required field", ToError(err, "en").Error())
+}
+
+type orderItem struct {
+	Sku string `json:"sku" binding:"required"`
+}
+
+type orderReq struct {
+	Items []orderItem `json:"items" binding:"dive"`
+	Page  int         `form:"page" binding:"max=100"`
+}
+
+func TestToDetail(t *testing.T) {
+	assert.Nil(t, InitTrans(EN))
+	req := &orderReq{Items: []orderItem{{Sku: "a"}, {}}, Page: 101}
+	errs := ToDetail(binding.Validator.ValidateStruct(req), req)
+	if assert.Len(t, errs, 2) {
+		assert.Equal(t, "items[1].sku", errs[0].Path)
+		assert.Equal(t, "required", errs[0].Tag)
+		assert.Equal(t, "page", errs[1].Path)
+		assert.Equal(t, "max", errs[1].Tag)
+		assert.Equal(t, "100", errs[1].Param)
+		assert.Equal(t, "Page must be 100 or less", errs[1].Msg)
+	}
+}
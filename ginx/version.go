@@ -0,0 +1,284 @@
+package ginx
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ytf606/golibs/errorx"
+	"github.com/ytf606/golibs/logx"
+)
+
+// ApiVersionKey gin.Context中保存实际使用的api版本的key
+const ApiVersionKey = "ginx_api_version"
+
+// VersionConfig 多版本路由配置
+type VersionConfig struct {
+	// Header 指定版本的请求头, 响应中也会返回实际使用的版本, 默认X-Api-Version
+	Header string
+	// MediaType Accept中的厂商媒体类型, 如application/vnd.demo
+	// 支持application/vnd.demo.v2+json和application/vnd.demo+json; version=2两种写法
+	MediaType string
+	// Default 请求未指定版本时使用的版本, 为空时使用该路由注册的最新版本
+	Default string
+	// Fallback 请求的版本没有注册该路由时回退到更低的最近版本, 否则返回ErrApiVersion
+	Fallback bool
+}
+
+// VersionOption 版本的废弃信息
+type VersionOption struct {
+	// Deprecated 返回Deprecation头并打印warning日志
+	Deprecated bool
+	// Sunset 下线时间, 非零时返回Sunset头
+	Sunset time.Time
+	// Link 迁移说明地址, 返回Link: <url>; rel="deprecation"
+	Link string
+}
+
+// VersionRouter 同一路由的多个版本, 按路径前缀(/v2/users)、请求头或Accept媒体类型(/users)选择
+//
+//	api := ginx.NewVersionRouter(app.Group("/api"), ginx.VersionConfig{Default: "v1", Fallback: true})
+//	api.Version("v1", ginx.VersionOption{Deprecated: true}).GET("/users", v1.Users)
+//	api.Version("v2").GET("/users", v2.Users)
+type VersionRouter struct {
+	group  *gin.RouterGroup
+	config VersionConfig
+
+	lock     sync.RWMutex
+	versions map[string]*VersionGroup
+	routes   map[string]*versionRoute
+}
+
+// VersionGroup 某个版本的路由注册入口
+type VersionGroup struct {
+	router *VersionRouter
+	name   string
+	option VersionOption
+}
+
+type versionRoute struct {
+	method   string
+	path     string
+	handlers map[string]gin.HandlersChain
+}
+
+func NewVersionRouter(group *gin.RouterGroup, config VersionConfig) *VersionRouter {
+	if config.Header == "" {
+		config.Header = "X-Api-Version"
+	}
+	if config.Default != "" {
+		config.Default = normalizeVersion(config.Default)
+	}
+	return &VersionRouter{
+		group:    group,
+		config:   config,
+		versions: make(map[string]*VersionGroup),
+		routes:   make(map[string]*versionRoute),
+	}
+}
+
+// Version 声明版本, name形如v1、v2.1, 重复声明时更新option并返回同一版本
+func (r *VersionRouter) Version(name string, option ...VersionOption) *VersionGroup {
+	name = normalizeVersion(name)
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	v, ok := r.versions[name]
+	if !ok {
+		v = &VersionGroup{router: r, name: name}
+		r.versions[name] = v
+		// 已注册的路由补充新版本的路径前缀, 以便Fallback生效
+		for _, route := range r.routes {
+			r.group.Handle(route.method, versionPath(name, route.path), r.dispatch(route, name))
+		}
+	}
+	if len(option) > 0 {
+		v.option = option[0]
+	}
+	return v
+}
+
+// Handle 注册该版本的handler, handlers依次执行直到Abort
+// 其中的c.Next()不会等待后续handler, 需要包裹handler的中间件请注册在group上
+func (v *VersionGroup) Handle(method, path string, handlers ...gin.HandlerFunc) *VersionGroup {
+	r := v.router
+	method = strings.ToUpper(method)
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	key := method + " " + path
+	route, ok := r.routes[key]
+	if !ok {
+		route = &versionRoute{method: method, path: path, handlers: make(map[string]gin.HandlersChain)}
+		r.routes[key] = route
+		r.group.Handle(method, path, r.dispatch(route, ""))
+		for name := range r.versions {
+			r.group.Handle(method, versionPath(name, path), r.dispatch(route, name))
+		}
+	}
+	route.handlers[v.name] = handlers
+	return v
+}
+
+func (v *VersionGroup) GET(path string, handlers ...gin.HandlerFunc) *VersionGroup {
+	return v.Handle(http.MethodGet, path, handlers...)
+}
+
+func (v *VersionGroup) POST(path string, handlers ...gin.HandlerFunc) *VersionGroup {
+	return v.Handle(http.MethodPost, path, handlers...)
+}
+
+func (v *VersionGroup) PUT(path string, handlers ...gin.HandlerFunc) *VersionGroup {
+	return v.Handle(http.MethodPut, path, handlers...)
+}
+
+func (v *VersionGroup) PATCH(path string, handlers ...gin.HandlerFunc) *VersionGroup {
+	return v.Handle(http.MethodPatch, path, handlers...)
+}
+
+func (v *VersionGroup) DELETE(path string, handlers ...gin.HandlerFunc) *VersionGroup {
+	return v.Handle(http.MethodDelete, path, handlers...)
+}
+
+// RequestApiVersion 请求实际使用的api版本
+func RequestApiVersion(c *gin.Context) string {
+	return c.GetString(ApiVersionKey)
+}
+
+// dispatch fixed为路径前缀中的版本, 为空时从请求头或Accept中取
+func (r *VersionRouter) dispatch(route *versionRoute, fixed string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requested := fixed
+		if requested == "" {
+			requested = r.requestVersion(c)
+		}
+		r.lock.RLock()
+		name, handlers := r.resolve(route, requested)
+		var option VersionOption
+		if v, ok := r.versions[name]; ok {
+			option = v.option
+		}
+		r.lock.RUnlock()
+		if handlers == nil {
+			ErrResponse(c, errorx.ErrApiVersion)
+			c.Abort()
+			return
+		}
+
+		c.Set(ApiVersionKey, name)
+		c.Header(r.config.Header, name)
+		if option.Deprecated {
+			c.Header("Deprecation", "true")
+			if option.Link != "" {
+				c.Header("Link", "<"+option.Link+`>; rel="deprecation"`)
+			}
+			logx.Wx(StdCtx(c), "[ginx_version]", "deprecated api version called version:%s, method:%s, path:%s, sunset:%v",
+				name, route.method, c.FullPath(), option.Sunset)
+		}
+		if !option.Sunset.IsZero() {
+			c.Header("Sunset", option.Sunset.UTC().Format(http.TimeFormat))
+		}
+		for _, h := range handlers {
+			if c.IsAborted() {
+				return
+			}
+			h(c)
+		}
+	}
+}
+
+// requestVersion 请求头优先, 其次Accept媒体类型
+func (r *VersionRouter) requestVersion(c *gin.Context) string {
+	if v := c.GetHeader(r.config.Header); v != "" {
+		return normalizeVersion(v)
+	}
+	if r.config.MediaType == "" {
+		return ""
+	}
+	for _, accept := range strings.Split(c.GetHeader("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(accept))
+		if err != nil || !strings.HasPrefix(mediaType, r.config.MediaType) {
+			continue
+		}
+		if v := params["version"]; v != "" {
+			return normalizeVersion(v)
+		}
+		// application/vnd.demo.v2+json
+		rest := strings.TrimPrefix(mediaType, r.config.MediaType)
+		if i := strings.IndexByte(rest, '+'); i >= 0 {
+			rest = rest[:i]
+		}
+		if rest = strings.TrimPrefix(rest, "."); rest != "" {
+			return normalizeVersion(rest)
+		}
+	}
+	return ""
+}
+
+func (r *VersionRouter) resolve(route *versionRoute, requested string) (string, gin.HandlersChain) {
+	if requested == "" {
+		requested = r.config.Default
+	}
+	if requested == "" {
+		latest := ""
+		for name := range route.handlers {
+			if latest == "" || compareVersion(name, latest) > 0 {
+				latest = name
+			}
+		}
+		return latest, route.handlers[latest]
+	}
+	if handlers, ok := route.handlers[requested]; ok {
+		return requested, handlers
+	}
+	if !r.config.Fallback {
+		return "", nil
+	}
+	best := ""
+	for name := range route.handlers {
+		if compareVersion(name, requested) < 0 && (best == "" || compareVersion(name, best) > 0) {
+			best = name
+		}
+	}
+	return best, route.handlers[best]
+}
+
+func versionPath(name, path string) string {
+	if path == "" || path == "/" {
+		return "/" + name
+	}
+	return "/" + name + path
+}
+
+// normalizeVersion 2、V2 => v2
+func normalizeVersion(v string) string {
+	v = strings.ToLower(strings.TrimSpace(v))
+	if !strings.HasPrefix(v, "v") {
+		v = "v" + v
+	}
+	return v
+}
+
+// compareVersion 按数字逐段比较, v2.1 > v2 > v1.10 > v1.9
+func compareVersion(a, b string) int {
+	as := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	bs := strings.Split(strings.TrimPrefix(b, "v"), ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,54 @@
+package ginx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVersionRouter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	api := NewVersionRouter(r.Group("/api"), VersionConfig{MediaType: "application/vnd.demo", Default: "v1", Fallback: true})
+	sunset := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	api.Version("v1", VersionOption{Deprecated: true, Sunset: sunset}).GET("/users", func(c *gin.Context) {
+		c.String(http.StatusOK, "users v1")
+	})
+	api.Version("v2").GET("/users", func(c *gin.Context) {
+		c.String(http.StatusOK, "users "+RequestApiVersion(c))
+	})
+	api.Version("v3")
+
+	do := func(target string, header map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do("/api/users", nil)
+	assert.Equal(t, "users v1", w.Body.String())
+	assert.Equal(t, "true", w.Header().Get("Deprecation"))
+	assert.Equal(t, "Tue, 01 Jan 2030 00:00:00 GMT", w.Header().Get("Sunset"))
+
+	assert.Equal(t, "users v2", do("/api/v2/users", nil).Body.String())
+	assert.Equal(t, "users v2", do("/api/users", map[string]string{"X-Api-Version": "2"}).Body.String())
+	assert.Equal(t, "users v2", do("/api/users", map[string]string{"Accept": "application/vnd.demo.v2+json"}).Body.String())
+	assert.Equal(t, "users v2", do("/api/users", map[string]string{"Accept": "application/vnd.demo+json; version=2"}).Body.String())
+
+	// v3未注册该路由, 回退到v2
+	w = do("/api/v3/users", nil)
+	assert.Equal(t, "users v2", w.Body.String())
+	assert.Equal(t, "v2", w.Header().Get("X-Api-Version"))
+	assert.Empty(t, w.Header().Get("Deprecation"))
+
+	api.config.Fallback = false
+	assert.Equal(t, http.StatusNotFound, do("/api/v3/users", nil).Code)
+}
@@ -0,0 +1,150 @@
+package wshub
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/ytf606/golibs/errorx"
+	"github.com/ytf606/golibs/logx"
+	"github.com/ytf606/golibs/service/kafka"
+	"github.com/ytf606/golibs/service/redis"
+)
+
+// Broker 多实例间分发消息, Publish的消息会被所有实例(包括自身)的Subscribe收到
+type Broker interface {
+	Publish(ctx context.Context, msg Message) error
+	// Subscribe 阻塞接收消息, ctx取消后返回nil, 连接异常时返回error由hub重试
+	Subscribe(ctx context.Context, handler func(msg Message)) error
+	Close() error
+}
+
+/****** redis pub/sub ******/
+
+type redisBroker struct {
+	client  *redis.Ins
+	channel string
+}
+
+// NewRedisBroker 基于redis pub/sub, 所有实例订阅同一channel
+func NewRedisBroker(client *redis.Ins, channel string) Broker {
+	return &redisBroker{client: client, channel: channel}
+}
+
+func (b *redisBroker) Publish(ctx context.Context, msg Message) error {
+	data, err := json.Marshal(&msg)
+	if err != nil {
+		return err
+	}
+	return b.client.Publish(ctx, b.channel, data).Err()
+}
+
+func (b *redisBroker) Subscribe(ctx context.Context, handler func(msg Message)) error {
+	sub := b.client.Subscribe(ctx, b.channel)
+	defer sub.Close()
+	// 等待订阅确认, 确认前发布的消息收不到
+	if _, err := sub.Receive(ctx); err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return err
+	}
+	ch := sub.Channel()
+	for {
+		select {
+		case m, ok := <-ch:
+			if !ok {
+				return fmt.Errorf("redis channel %s closed", b.channel)
+			}
+			var msg Message
+			if err := json.Unmarshal([]byte(m.Payload), &msg); err != nil {
+				logx.Ex(ctx, "ginx.wshub.redis", "decode message failed err:%+v, channel:%s", err, b.channel)
+				continue
+			}
+			handler(msg)
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func (b *redisBroker) Close() error {
+	return nil
+}
+
+/****** kafka topic ******/
+
+type kafkaBroker struct {
+	producer kafka.Producer
+	config   kafka.ConsumerConfig
+}
+
+// NewKafkaBroker 基于kafka topic, 每个实例使用独立的consumer group以收到全部消息
+// config.GroupID为空时按topic和主机名生成, 重启后复用同一group, 默认从最新位置消费
+// 注意: 每个不同的GroupID都会在broker保留一份offset, 主机名随发布变化(如k8s Deployment)时
+// 旧group不会自动清理, 只能等offsets.retention.minutes过期, 此时应显式指定稳定的GroupID;
+// 同一主机多进程时也需显式区分GroupID, 否则会分摊消息
+func NewKafkaBroker(ctx context.Context, config kafka.ConsumerConfig) (Broker, error) {
+	if config.GroupID == "" {
+		host, err := os.Hostname()
+		if err != nil {
+			return nil, errorx.Wrap500Response(err, errorx.KafkaInitConsumerReaderErr, "")
+		}
+		config.GroupID = fmt.Sprintf("%s-wshub-%s", config.Topic, host)
+	}
+	producer, err := kafka.NewProducer(ctx, &kafka.ProducerConfig{Brokers: config.Brokers, Topic: config.Topic})
+	if err != nil {
+		return nil, err
+	}
+	if config.Offset == 0 {
+		config.Offset = kafka.MessageTypeLatest
+	}
+	return &kafkaBroker{producer: producer, config: config}, nil
+}
+
+func (b *kafkaBroker) Publish(ctx context.Context, msg Message) error {
+	data, err := json.Marshal(&msg)
+	if err != nil {
+		return err
+	}
+	return b.producer.SyncWriter(ctx, &kafka.ProducerMessage{Value: data})
+}
+
+func (b *kafkaBroker) Subscribe(ctx context.Context, handler func(msg Message)) error {
+	config := b.config
+	consumer := kafka.NewConsumer(ctx, &config)
+	defer consumer.Close(ctx)
+	messages, err := consumer.GroupReader(ctx)
+	if err != nil {
+		return err
+	}
+	for {
+		select {
+		case m, ok := <-messages:
+			if !ok {
+				return fmt.Errorf("kafka topic %s reader closed", config.Topic)
+			}
+			if m == nil {
+				continue
+			}
+			var msg Message
+			if err := json.Unmarshal(m.Value, &msg); err != nil {
+				logx.Ex(ctx, "ginx.wshub.kafka", "decode message failed err:%+v, topic:%s", err, config.Topic)
+			} else {
+				handler(msg)
+			}
+			consumer.CommitMessage(ctx, m)
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func (b *kafkaBroker) Close() error {
+	err := b.producer.Close(context.Background())
+	if res := errorx.UnWrapResponse(err); res != nil && res.Err == nil {
+		return nil
+	}
+	return err
+}
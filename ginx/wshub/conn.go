@@ -0,0 +1,194 @@
+package wshub
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/ytf606/golibs/errorx"
+	"github.com/ytf606/golibs/logx"
+	"github.com/ytf606/golibs/service/jwtx"
+)
+
+type outbound struct {
+	messageType int
+	data        []byte
+}
+
+// Conn hub中的一个websocket连接, 写操作全部经由发送队列在writePump中完成
+type Conn struct {
+	Id     string
+	UserId string
+	Claims jwtx.Claims
+
+	hub  *Hub
+	ws   *websocket.Conn
+	ctx  context.Context
+	send chan outbound
+	done chan struct{}
+
+	lock      sync.RWMutex
+	rooms     map[string]struct{}
+	values    map[string]interface{}
+	closeOnce sync.Once
+}
+
+func newConn(h *Hub, ws *websocket.Conn, ctx context.Context, userId string, claims jwtx.Claims) *Conn {
+	return &Conn{
+		Id:     newConnId(),
+		UserId: userId,
+		Claims: claims,
+		hub:    h,
+		ws:     ws,
+		ctx:    ctx,
+		send:   make(chan outbound, h.config.SendQueueSize),
+		done:   make(chan struct{}),
+		rooms:  make(map[string]struct{}),
+		values: make(map[string]interface{}),
+	}
+}
+
+// Context 升级请求的context, 携带trace信息, 用于记录日志
+func (c *Conn) Context() context.Context {
+	return c.ctx
+}
+
+// Send 放入发送队列, 队列满时按Config.Overflow处理
+func (c *Conn) Send(messageType int, data []byte) error {
+	select {
+	case <-c.done:
+		return errorx.ErrWsConnClosed
+	default:
+	}
+	select {
+	case c.send <- outbound{messageType: messageType, data: data}:
+		return nil
+	case <-c.done:
+		return errorx.ErrWsConnClosed
+	default:
+	}
+	if c.hub.config.Overflow == OverflowClose {
+		logx.Wx(c.ctx, "ginx.wshub.send", "send queue full, close conn:%s, user:%s", c.Id, c.UserId)
+		c.Close()
+	}
+	return errorx.ErrWsSendQueueFull
+}
+
+// Close 从hub中移除并通知writePump关闭连接, 可重复调用
+// writePump写完队列中的消息和关闭帧(1000)后关闭socket
+func (c *Conn) Close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		c.hub.unregister(c)
+	})
+}
+
+// Done 连接关闭时关闭
+func (c *Conn) Done() <-chan struct{} {
+	return c.done
+}
+
+func (c *Conn) Set(key string, value interface{}) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.values[key] = value
+}
+
+func (c *Conn) Get(key string) (interface{}, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	v, ok := c.values[key]
+	return v, ok
+}
+
+// Rooms 已加入的房间
+func (c *Conn) Rooms() []string {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	rooms := make([]string, 0, len(c.rooms))
+	for room := range c.rooms {
+		rooms = append(rooms, room)
+	}
+	return rooms
+}
+
+func (c *Conn) addRoom(room string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.rooms[room] = struct{}{}
+}
+
+func (c *Conn) delRoom(room string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	delete(c.rooms, room)
+}
+
+func (c *Conn) readPump() {
+	defer c.Close()
+	config := c.hub.config
+	c.ws.SetReadLimit(config.MaxMessageSize)
+	c.ws.SetReadDeadline(time.Now().Add(config.PongTimeout))
+	c.ws.SetPongHandler(func(string) error {
+		return c.ws.SetReadDeadline(time.Now().Add(config.PongTimeout))
+	})
+	for {
+		messageType, data, err := c.ws.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure, websocket.CloseNoStatusReceived) {
+				logx.Wx(c.ctx, "ginx.wshub.read", "read failed conn:%s, user:%s, err:%+v", c.Id, c.UserId, err)
+			}
+			return
+		}
+		c.ws.SetReadDeadline(time.Now().Add(config.PongTimeout))
+		if config.OnMessage != nil {
+			config.OnMessage(c, messageType, data)
+		}
+	}
+}
+
+func (c *Conn) writePump() {
+	config := c.hub.config
+	ticker := time.NewTicker(config.PingInterval)
+	defer func() {
+		ticker.Stop()
+		c.Close()
+		c.shutdown()
+	}()
+	for {
+		select {
+		case msg := <-c.send:
+			c.ws.SetWriteDeadline(time.Now().Add(config.WriteTimeout))
+			if err := c.ws.WriteMessage(msg.messageType, msg.data); err != nil {
+				logx.Wx(c.ctx, "ginx.wshub.write", "write failed conn:%s, user:%s, err:%+v", c.Id, c.UserId, err)
+				return
+			}
+		case <-ticker.C:
+			if err := c.ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(config.WriteTimeout)); err != nil {
+				return
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// shutdown 在WriteTimeout内写出队列中剩余的消息和关闭帧, 然后关闭socket, 只由写协程调用
+func (c *Conn) shutdown() {
+	deadline := time.Now().Add(c.hub.config.WriteTimeout)
+	c.ws.SetWriteDeadline(deadline)
+	for drained := false; !drained; {
+		select {
+		case msg := <-c.send:
+			if err := c.ws.WriteMessage(msg.messageType, msg.data); err != nil {
+				drained = true
+			}
+		default:
+			drained = true
+		}
+	}
+	c.ws.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), deadline)
+	c.ws.Close()
+}
@@ -0,0 +1,384 @@
+package wshub
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	uuid "github.com/satori/go.uuid"
+	"github.com/ytf606/golibs/errorx"
+	"github.com/ytf606/golibs/ginx"
+	"github.com/ytf606/golibs/ginx/middleware"
+	"github.com/ytf606/golibs/logx"
+	"github.com/ytf606/golibs/service/jwtx"
+)
+
+// OverflowPolicy 连接发送队列满时的处理方式
+type OverflowPolicy int
+
+const (
+	// OverflowDrop 丢弃新消息, Send返回errorx.ErrWsSendQueueFull
+	OverflowDrop OverflowPolicy = iota
+	// OverflowClose 关闭慢连接
+	OverflowClose
+)
+
+// Config hub配置, 零值字段使用默认值
+type Config struct {
+	// PingInterval 服务端发送ping的间隔, 默认30s
+	PingInterval time.Duration
+	// PongTimeout 超过该时间未收到任何消息(含pong)断开, 默认PingInterval*2
+	PongTimeout time.Duration
+	// WriteTimeout 单次写超时, 默认10s
+	WriteTimeout time.Duration
+	// MaxMessageSize 单条消息最大字节数, 默认64KB
+	MaxMessageSize int64
+	// SendQueueSize 每个连接的发送队列长度, 默认256
+	SendQueueSize int
+	Overflow      OverflowPolicy
+
+	// Broker 不为nil时消息经由Broker分发到所有实例, 实现跨实例的广播和按用户发送
+	Broker Broker
+
+	// Cors 按CORS配置校验Origin, 为nil时只允许同源
+	Cors *middleware.Config
+
+	// Jwt 不为nil时升级前校验token, token取自Authorization: Bearer头或TokenQuery参数
+	Jwt jwtx.Jwter
+	// TokenQuery 默认token
+	TokenQuery string
+	// NewClaims 每次解析token用的claims, 默认jwtx.MapClaims
+	NewClaims func() jwtx.Claims
+	// UserId 从claims中取用户标识, 默认取MapClaims的sub
+	UserId func(claims jwtx.Claims) string
+
+	OnConnect func(conn *Conn)
+	OnMessage func(conn *Conn, messageType int, data []byte)
+	OnClose   func(conn *Conn)
+}
+
+// Message 投递的消息, ConnId、UserId、Room均为空时广播给所有连接
+type Message struct {
+	Type   int    `json:"type"`
+	Data   []byte `json:"data"`
+	ConnId string `json:"conn_id,omitempty"`
+	UserId string `json:"user_id,omitempty"`
+	Room   string `json:"room,omitempty"`
+}
+
+// Hub websocket连接管理, 按连接、用户、房间索引
+type Hub struct {
+	config   Config
+	upgrader websocket.Upgrader
+
+	lock   sync.RWMutex
+	conns  map[string]*Conn
+	users  map[string]map[string]*Conn
+	rooms  map[string]map[string]*Conn
+	closed bool
+
+	cancel context.CancelFunc
+}
+
+func NewHub(config Config) *Hub {
+	if config.PingInterval <= 0 {
+		config.PingInterval = 30 * time.Second
+	}
+	if config.PongTimeout <= 0 {
+		config.PongTimeout = config.PingInterval * 2
+	}
+	if config.WriteTimeout <= 0 {
+		config.WriteTimeout = 10 * time.Second
+	}
+	if config.MaxMessageSize <= 0 {
+		config.MaxMessageSize = 64 << 10
+	}
+	if config.SendQueueSize <= 0 {
+		config.SendQueueSize = 256
+	}
+	if config.TokenQuery == "" {
+		config.TokenQuery = "token"
+	}
+	if config.NewClaims == nil {
+		config.NewClaims = func() jwtx.Claims { return jwtx.MapClaims{} }
+	}
+	if config.UserId == nil {
+		config.UserId = func(claims jwtx.Claims) string {
+			if m, ok := claims.(jwtx.MapClaims); ok {
+				sub, _ := m["sub"].(string)
+				return sub
+			}
+			if s, ok := claims.(*jwtx.StandardClaims); ok {
+				return s.Subject
+			}
+			return ""
+		}
+	}
+	h := &Hub{
+		config: config,
+		conns:  make(map[string]*Conn),
+		users:  make(map[string]map[string]*Conn),
+		rooms:  make(map[string]map[string]*Conn),
+	}
+	// Origin在Handler中校验, 以便返回统一的错误响应
+	h.upgrader.CheckOrigin = func(r *http.Request) bool { return true }
+	if config.Broker != nil {
+		var ctx context.Context
+		ctx, h.cancel = context.WithCancel(context.Background())
+		go h.subscribe(ctx)
+	}
+	return h
+}
+
+// subscribe 接收Broker消息在本实例投递, 异常断开后重试
+func (h *Hub) subscribe(ctx context.Context) {
+	logTag := "ginx.wshub.subscribe"
+	for {
+		err := h.config.Broker.Subscribe(ctx, h.deliver)
+		if ctx.Err() != nil {
+			return
+		}
+		logx.Ex(ctx, logTag, "broker subscribe failed, retry later err:%+v", err)
+		select {
+		case <-time.After(time.Second):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Handler 校验Origin和token后升级连接, 阻塞到连接关闭
+func (h *Hub) Handler() gin.HandlerFunc {
+	logTag := "ginx.wshub.handler"
+	return func(c *gin.Context) {
+		if !h.checkOrigin(c.Request) {
+			logx.Wx(c, logTag, "origin not allowed origin:%s", c.GetHeader("Origin"))
+			ginx.ErrResponse(c, errorx.ErrWsOrigin)
+			c.Abort()
+			return
+		}
+
+		var claims jwtx.Claims
+		userId := ""
+		if h.config.Jwt != nil {
+			claims = h.config.NewClaims()
+			token, err := h.config.Jwt.Parse(c, h.token(c), claims)
+			if err == nil && (token == nil || !token.Valid) {
+				err = errorx.ErrJwtTokenInvalid
+			}
+			if err != nil {
+				ginx.ErrResponse(c, err)
+				c.Abort()
+				return
+			}
+			userId = h.config.UserId(claims)
+		}
+
+		ws, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
+		if err != nil {
+			// Upgrade失败时已写出错误响应
+			logx.Wx(c, logTag, "upgrade failed err:%+v", err)
+			c.Abort()
+			return
+		}
+
+		conn := newConn(h, ws, ginx.StdCtx(c), userId, claims)
+		if !h.register(conn) {
+			conn.Close()
+			conn.shutdown()
+			return
+		}
+		if h.config.OnConnect != nil {
+			h.config.OnConnect(conn)
+		}
+		go conn.writePump()
+		conn.readPump()
+	}
+}
+
+func (h *Hub) token(c *gin.Context) string {
+	if auth := c.GetHeader("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimPrefix(auth, "Bearer ")
+	}
+	return c.Query(h.config.TokenQuery)
+}
+
+func (h *Hub) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" || origin == "http://"+r.Host || origin == "https://"+r.Host {
+		return true
+	}
+	return h.config.Cors != nil && h.config.Cors.AllowOrigin(origin)
+}
+
+func (h *Hub) register(conn *Conn) bool {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if h.closed {
+		return false
+	}
+	h.conns[conn.Id] = conn
+	if conn.UserId != "" {
+		addIndex(h.users, conn.UserId, conn)
+	}
+	return true
+}
+
+func (h *Hub) unregister(conn *Conn) {
+	h.lock.Lock()
+	delete(h.conns, conn.Id)
+	if conn.UserId != "" {
+		delIndex(h.users, conn.UserId, conn)
+	}
+	for _, room := range conn.Rooms() {
+		delIndex(h.rooms, room, conn)
+	}
+	h.lock.Unlock()
+
+	if h.config.OnClose != nil {
+		h.config.OnClose(conn)
+	}
+}
+
+// Join 加入房间
+func (h *Hub) Join(conn *Conn, room string) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if _, ok := h.conns[conn.Id]; !ok {
+		return
+	}
+	conn.addRoom(room)
+	addIndex(h.rooms, room, conn)
+}
+
+// Leave 离开房间
+func (h *Hub) Leave(conn *Conn, room string) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	conn.delRoom(room)
+	delIndex(h.rooms, room, conn)
+}
+
+// Broadcast 发送给所有连接
+func (h *Hub) Broadcast(ctx context.Context, messageType int, data []byte) error {
+	return h.Publish(ctx, Message{Type: messageType, Data: data})
+}
+
+// SendToRoom 发送给房间内所有连接
+func (h *Hub) SendToRoom(ctx context.Context, room string, messageType int, data []byte) error {
+	return h.Publish(ctx, Message{Type: messageType, Data: data, Room: room})
+}
+
+// SendToUser 发送给用户的所有连接
+func (h *Hub) SendToUser(ctx context.Context, userId string, messageType int, data []byte) error {
+	return h.Publish(ctx, Message{Type: messageType, Data: data, UserId: userId})
+}
+
+// SendToConn 发送给指定连接
+func (h *Hub) SendToConn(ctx context.Context, connId string, messageType int, data []byte) error {
+	return h.Publish(ctx, Message{Type: messageType, Data: data, ConnId: connId})
+}
+
+// Publish 按消息中的目标投递, 配置了Broker时经由Broker分发到所有实例
+func (h *Hub) Publish(ctx context.Context, msg Message) error {
+	if h.config.Broker == nil {
+		h.deliver(msg)
+		return nil
+	}
+	if err := h.config.Broker.Publish(ctx, msg); err != nil {
+		logx.Ex(ctx, "ginx.wshub.publish", "broker publish failed err:%+v, msg:%+v", err, msg)
+		return err
+	}
+	return nil
+}
+
+func (h *Hub) deliver(msg Message) {
+	for _, conn := range h.targets(msg) {
+		if err := conn.Send(msg.Type, msg.Data); err != nil && err != errorx.ErrWsConnClosed {
+			logx.Wx(conn.Context(), "ginx.wshub.deliver", "send failed conn:%s, user:%s, err:%+v", conn.Id, conn.UserId, err)
+		}
+	}
+}
+
+func (h *Hub) targets(msg Message) []*Conn {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+	var index map[string]*Conn
+	switch {
+	case msg.ConnId != "":
+		if conn, ok := h.conns[msg.ConnId]; ok {
+			return []*Conn{conn}
+		}
+		return nil
+	case msg.UserId != "":
+		index = h.users[msg.UserId]
+	case msg.Room != "":
+		index = h.rooms[msg.Room]
+	default:
+		index = h.conns
+	}
+	conns := make([]*Conn, 0, len(index))
+	for _, conn := range index {
+		conns = append(conns, conn)
+	}
+	return conns
+}
+
+// Conn 按id获取本实例上的连接
+func (h *Hub) Conn(connId string) (*Conn, bool) {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+	conn, ok := h.conns[connId]
+	return conn, ok
+}
+
+// Count 本实例上的连接数
+func (h *Hub) Count() int {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+	return len(h.conns)
+}
+
+// Close 关闭所有连接和Broker, 之后不再接受新连接
+func (h *Hub) Close() {
+	if h.cancel != nil {
+		h.cancel()
+		h.config.Broker.Close()
+	}
+	h.lock.Lock()
+	h.closed = true
+	conns := make([]*Conn, 0, len(h.conns))
+	for _, conn := range h.conns {
+		conns = append(conns, conn)
+	}
+	h.lock.Unlock()
+	for _, conn := range conns {
+		conn.Close()
+	}
+}
+
+func addIndex(index map[string]map[string]*Conn, key string, conn *Conn) {
+	m, ok := index[key]
+	if !ok {
+		m = make(map[string]*Conn)
+		index[key] = m
+	}
+	m[conn.Id] = conn
+}
+
+func delIndex(index map[string]map[string]*Conn, key string, conn *Conn) {
+	if m, ok := index[key]; ok {
+		delete(m, conn.Id)
+		if len(m) == 0 {
+			delete(index, key)
+		}
+	}
+}
+
+func newConnId() string {
+	return strings.Replace(uuid.NewV4().String(), "-", "", -1)
+}
@@ -0,0 +1,158 @@
+package wshub
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/ytf606/golibs/ginx/middleware"
+	"github.com/ytf606/golibs/service/jwtx"
+)
+
+func TestHub(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	j := jwtx.NewJwt("secret")
+	joined := make(chan *Conn, 2)
+	hub := NewHub(Config{
+		Jwt:  j,
+		Cors: &middleware.Config{AllowOrigins: []string{"https://app.example.com"}},
+		OnConnect: func(conn *Conn) {
+			joined <- conn
+		},
+		OnMessage: func(conn *Conn, messageType int, data []byte) {
+			conn.hub.Join(conn, string(data))
+		},
+	})
+	defer hub.Close()
+
+	r := gin.New()
+	r.GET("/ws", hub.Handler())
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws"
+
+	dial := func(sub, origin string) (*websocket.Conn, int) {
+		token, _ := j.Create(context.Background(), jwtx.MapClaims{"sub": sub})
+		header := http.Header{}
+		header.Set("Origin", origin)
+		ws, resp, _ := websocket.DefaultDialer.Dial(url+"?token="+token, header)
+		if resp == nil {
+			return ws, 0
+		}
+		return ws, resp.StatusCode
+	}
+
+	_, code := dial("u1", "https://evil.example.com")
+	assert.Equal(t, http.StatusForbidden, code)
+	_, resp, _ := websocket.DefaultDialer.Dial(url+"?token=bad", nil)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	ws1, code := dial("u1", "https://app.example.com")
+	assert.Equal(t, http.StatusSwitchingProtocols, code)
+	defer ws1.Close()
+	ws2, _ := dial("u2", "https://app.example.com")
+	defer ws2.Close()
+	conns := map[string]*Conn{}
+	for i := 0; i < 2; i++ {
+		conn := <-joined
+		conns[conn.UserId] = conn
+	}
+	assert.Equal(t, 2, hub.Count())
+
+	ws1.WriteMessage(websocket.TextMessage, []byte("room1"))
+	assert.Eventually(t, func() bool {
+		hub.lock.RLock()
+		defer hub.lock.RUnlock()
+		return len(hub.rooms["room1"]) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	hub.SendToRoom(context.Background(), "room1", websocket.TextMessage, []byte("to room"))
+	hub.SendToUser(context.Background(), "u2", websocket.TextMessage, []byte("to u2"))
+	read := func(ws *websocket.Conn) string {
+		ws.SetReadDeadline(time.Now().Add(time.Second))
+		_, data, err := ws.ReadMessage()
+		assert.Nil(t, err)
+		return string(data)
+	}
+	assert.Equal(t, "to room", read(ws1))
+	assert.Equal(t, "to u2", read(ws2))
+
+	hub.Broadcast(context.Background(), websocket.TextMessage, []byte("all"))
+	assert.Equal(t, "all", read(ws1))
+	assert.Equal(t, "all", read(ws2))
+
+	ws2.Close()
+	assert.Eventually(t, func() bool { return hub.Count() == 1 }, time.Second, 10*time.Millisecond)
+
+	// 服务端关闭时先写完队列中的消息, 客户端收到1000
+	assert.Nil(t, conns["u1"].Send(websocket.TextMessage, []byte("bye")))
+	conns["u1"].Close()
+	assert.Equal(t, "bye", read(ws1))
+	ws1.SetReadDeadline(time.Now().Add(time.Second))
+	_, _, err := ws1.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseNormalClosure), "%v", err)
+	assert.Eventually(t, func() bool { return hub.Count() == 0 }, time.Second, 10*time.Millisecond)
+}
+
+type memBroker struct {
+	lock     sync.Mutex
+	handlers []func(msg Message)
+}
+
+func (b *memBroker) Publish(ctx context.Context, msg Message) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	for _, h := range b.handlers {
+		h(msg)
+	}
+	return nil
+}
+
+func (b *memBroker) Subscribe(ctx context.Context, handler func(msg Message)) error {
+	b.lock.Lock()
+	b.handlers = append(b.handlers, handler)
+	b.lock.Unlock()
+	<-ctx.Done()
+	return nil
+}
+
+func (b *memBroker) Close() error {
+	return nil
+}
+
+func TestHubBroker(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	broker := &memBroker{}
+	connected := make(chan struct{}, 1)
+	hub1 := NewHub(Config{Broker: broker})
+	hub2 := NewHub(Config{Broker: broker, OnConnect: func(conn *Conn) { connected <- struct{}{} }})
+	defer hub1.Close()
+	defer hub2.Close()
+
+	r := gin.New()
+	r.GET("/ws", hub2.Handler())
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/ws", nil)
+	assert.Nil(t, err)
+	defer ws.Close()
+	<-connected
+	assert.Eventually(t, func() bool {
+		broker.lock.Lock()
+		defer broker.lock.Unlock()
+		return len(broker.handlers) == 2
+	}, time.Second, 10*time.Millisecond)
+
+	assert.Nil(t, hub1.Broadcast(context.Background(), websocket.TextMessage, []byte("from hub1")))
+	ws.SetReadDeadline(time.Now().Add(time.Second))
+	_, data, err := ws.ReadMessage()
+	assert.Nil(t, err)
+	assert.Equal(t, "from hub1", string(data))
+}
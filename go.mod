@@ -32,6 +32,7 @@ require (
 
 require (
 	github.com/Unknwon/goconfig v1.0.0
+	github.com/andybalholm/brotli v1.0.4
 	github.com/go-kratos/kratos/v2 v2.5.4
 	github.com/kardianos/osext v0.0.0-20190222173326-2bc1f35cddc0
 	github.com/smartystreets/goconvey v1.8.1 // indirect
@@ -21,7 +21,7 @@ type DefaultBuilder struct {
 var hostname string
 
 func (this *DefaultBuilder) LoggerX(ctx context.Context, lvl string, tag string, args interface{}, v ...interface{}) {
-	if !logutils.ValidLevel(lvl) {
+	if !logutils.ValidLevelCtx(ctx, lvl) {
 		return
 	}
 	if tag == "" {
@@ -35,29 +35,84 @@ func (this *DefaultBuilder) LoggerX(ctx context.Context, lvl string, tag string,
 
 	tag = logutils.Filter(tag)
 	position, message := this.Build(ctx, args, v...)
+	// 耗时同时作为CostField传给writer, json格式据此拆出cost
+	var fields []log4go.Field
 
 	if startValue := ctx.Value("start"); startValue != nil {
 		if start, ok := startValue.(time.Time); ok {
 			cost := time.Now().Sub(start)
-			message = message + " COST:" + fmt.Sprintf("%.2f", cost.Seconds()*1e3)
+			costValue := fmt.Sprintf("%.2f", cost.Seconds()*1e3)
+			message = message + " COST:" + costValue
+			fields = []log4go.Field{log4go.NewCostField(costValue)}
 		}
 	}
 
+	// 单个请求开启了调试日志
+	if floor, gate, ok := logutils.Escalated(ctx); ok {
+		if level, ok := logutils.LevelMap[lvl]; ok {
+			log4go.LogFloor(level, floor, gate, position, tag+"\t"+message, nil, fields)
+		}
+		if lvl == "FATAL" {
+			panic(message)
+		}
+		return
+	}
+
 	switch lvl {
 	case "DEBUG":
-		log4go.Log(log4go.DEBUG, position, tag+"\t"+message)
+		log4go.LogFields(log4go.DEBUG, position, tag+"\t"+message, nil, fields)
 	case "TRACE":
-		log4go.Log(log4go.TRACE, position, tag+"\t"+message)
+		log4go.LogFields(log4go.TRACE, position, tag+"\t"+message, nil, fields)
 	case "INFO":
-		log4go.Log(log4go.INFO, position, tag+"\t"+message)
+		log4go.LogFields(log4go.INFO, position, tag+"\t"+message, nil, fields)
 	case "WARNING":
-		log4go.Log(log4go.WARNING, position, tag+"\t"+message)
+		log4go.LogFields(log4go.WARNING, position, tag+"\t"+message, nil, fields)
 	case "ERROR":
-		log4go.Log(log4go.ERROR, position, tag+"\t"+message)
+		log4go.LogFields(log4go.ERROR, position, tag+"\t"+message, nil, fields)
 	case "CRITICAL":
-		log4go.Log(log4go.CRITICAL, position, tag+"\t"+message)
+		log4go.LogFields(log4go.CRITICAL, position, tag+"\t"+message, nil, fields)
 	case "FATAL":
-		log4go.Log(log4go.CRITICAL, position, tag+"\t"+message)
+		log4go.LogFields(log4go.CRITICAL, position, tag+"\t"+message, nil, fields)
+		panic(message)
+	}
+}
+
+// LoggerFields 结构化字段随LogRecord传给writer, 不拼接到消息中
+func (this *DefaultBuilder) LoggerFields(ctx context.Context, lvl string, tag string, msg string, fields []log4go.Field) {
+	if !logutils.ValidLevelCtx(ctx, lvl) {
+		return
+	}
+	level, ok := logutils.LevelMap[lvl]
+	if !ok {
+		return
+	}
+	if tag == "" {
+		tag = "NoTagError"
+	}
+
+	if ctx == nil {
+		id := strconv.FormatInt(logutils.GenLoggerId(), 10)
+		ctx = context.WithValue(context.Background(), "logid", id)
+	}
+
+	tag = logutils.Filter(tag)
+	position, message := this.Build(ctx, msg)
+
+	if startValue := ctx.Value("start"); startValue != nil {
+		if start, ok := startValue.(time.Time); ok {
+			cost := time.Now().Sub(start)
+			costValue := fmt.Sprintf("%.2f", cost.Seconds()*1e3)
+			message = message + " COST:" + costValue
+			fields = append(fields[:len(fields):len(fields)], log4go.NewCostField(costValue))
+		}
+	}
+
+	if floor, gate, ok := logutils.Escalated(ctx); ok {
+		log4go.LogFloor(level, floor, gate, position, tag+"\t"+message, nil, fields)
+	} else {
+		log4go.LogFields(level, position, tag+"\t"+message, nil, fields)
+	}
+	if lvl == "FATAL" {
 		panic(message)
 	}
 }
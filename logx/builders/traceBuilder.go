@@ -22,7 +22,7 @@ type TraceBuilder struct {
 }
 
 func (this *TraceBuilder) LoggerX(ctx context.Context, lvl string, tag string, args interface{}, v ...interface{}) {
-	if !logutils.ValidLevel(lvl) {
+	if !logutils.ValidLevelCtx(ctx, lvl) {
 		return
 	}
 	if tag == "" {
@@ -34,46 +34,103 @@ func (this *TraceBuilder) LoggerX(ctx context.Context, lvl string, tag string, a
 		ctx = context.WithValue(context.Background(), "logid", id)
 	}
 
-	if logutils.LogLevel(cast.ToString(ctx.Value("logLevel"))) > logutils.LogLevel(lvl) {
-		return
-	}
-
 	tag = logutils.Filter(tag)
 	position, message := this.Build(ctx, args, v...)
+	// 耗时同时作为CostField传给writer, json格式据此拆出cost
+	var fields []log4go.Field
 
 	if startValue := ctx.Value("start"); startValue != nil {
 		if start, ok := startValue.(time.Time); ok {
 			cost := time.Now().Sub(start)
-			message = message + " COST:" + fmt.Sprintf("%.6f", cost.Seconds())
+			costValue := fmt.Sprintf("%.6f", cost.Seconds())
+			message = message + " COST:" + costValue
+			fields = []log4go.Field{log4go.NewCostField(costValue)}
 		}
 	}
-	traceNode := logtrace.ExtractTraceNodeFromContext(ctx)
-	metadata := traceNode.ForkMap()
-	metadata["x_department"] = this.department
-	metadata["x_version"] = this.version
-	metadata["x_module"] = `"` + tag + `"`
-	metadata["x_org_id"] = `"` + cast.ToString(ctx.Value("org_id")) + `"`
-	logtrace.IncrementRpcId(ctx)
+	metadata := this.metadata(ctx, tag)
+	// 单个请求开启了调试日志
+	if floor, gate, ok := logutils.Escalated(ctx); ok {
+		if level, ok := logutils.LevelMap[lvl]; ok {
+			log4go.LogFloor(level, floor, gate, position, tag+"\t"+message, metadata, fields)
+		}
+		if lvl == "FATAL" {
+			panic(message)
+		}
+		return
+	}
 	switch lvl {
 	case "DEBUG":
-		log4go.LogTraceMap(log4go.DEBUG, position, tag+"\t"+message, metadata)
+		log4go.LogFields(log4go.DEBUG, position, tag+"\t"+message, metadata, fields)
 	case "TRACE":
-		log4go.LogTraceMap(log4go.TRACE, position, tag+"\t"+message, metadata)
+		log4go.LogFields(log4go.TRACE, position, tag+"\t"+message, metadata, fields)
 	case "INFO":
-		log4go.LogTraceMap(log4go.INFO, position, tag+"\t"+message, metadata)
+		log4go.LogFields(log4go.INFO, position, tag+"\t"+message, metadata, fields)
 	case "WARNING":
-		log4go.LogTraceMap(log4go.WARNING, position, tag+"\t"+message, metadata)
+		log4go.LogFields(log4go.WARNING, position, tag+"\t"+message, metadata, fields)
 	case "ERROR":
-		log4go.LogTraceMap(log4go.ERROR, position, tag+"\t"+message, metadata)
+		log4go.LogFields(log4go.ERROR, position, tag+"\t"+message, metadata, fields)
 	case "CRITICAL":
-		log4go.LogTraceMap(log4go.CRITICAL, position, tag+"\t"+message, metadata)
+		log4go.LogFields(log4go.CRITICAL, position, tag+"\t"+message, metadata, fields)
 	case "FATAL":
-		log4go.LogTraceMap(log4go.CRITICAL, position, tag+"\t"+message, metadata)
+		log4go.LogFields(log4go.CRITICAL, position, tag+"\t"+message, metadata, fields)
 		panic(message)
 	}
 
 }
 
+// LoggerFields 结构化字段随LogRecord传给writer, 不拼接到消息中
+func (this *TraceBuilder) LoggerFields(ctx context.Context, lvl string, tag string, msg string, fields []log4go.Field) {
+	if !logutils.ValidLevelCtx(ctx, lvl) {
+		return
+	}
+	if tag == "" {
+		tag = "NoTagError"
+	}
+
+	if ctx == nil {
+		id := strconv.FormatInt(logutils.GenLoggerId(), 10)
+		ctx = context.WithValue(context.Background(), "logid", id)
+	}
+	level, ok := logutils.LevelMap[lvl]
+	if !ok {
+		return
+	}
+
+	tag = logutils.Filter(tag)
+	position, message := this.Build(ctx, msg)
+
+	if startValue := ctx.Value("start"); startValue != nil {
+		if start, ok := startValue.(time.Time); ok {
+			cost := time.Now().Sub(start)
+			costValue := fmt.Sprintf("%.6f", cost.Seconds())
+			message = message + " COST:" + costValue
+			fields = append(fields[:len(fields):len(fields)], log4go.NewCostField(costValue))
+		}
+	}
+	if floor, gate, ok := logutils.Escalated(ctx); ok {
+		log4go.LogFloor(level, floor, gate, position, tag+"\t"+message, this.metadata(ctx, tag), fields)
+	} else {
+		log4go.LogFields(level, position, tag+"\t"+message, this.metadata(ctx, tag), fields)
+	}
+	if lvl == "FATAL" {
+		panic(message)
+	}
+}
+
+func (this *TraceBuilder) metadata(ctx context.Context, tag string) map[string]string {
+	traceNode := logtrace.ExtractTraceNodeFromContext(ctx)
+	metadata := traceNode.ForkMap()
+	metadata["x_department"] = this.department
+	metadata["x_version"] = this.version
+	metadata["x_module"] = `"` + tag + `"`
+	metadata["x_org_id"] = `"` + cast.ToString(ctx.Value("org_id")) + `"`
+	if spanId := logtrace.SpanIdFromContext(ctx); spanId != "" {
+		metadata["x_span_id"] = `"` + spanId + `"`
+	}
+	logtrace.IncrementRpcId(ctx)
+	return metadata
+}
+
 func (this *TraceBuilder) Build(ctx context.Context, args interface{}, v ...interface{}) (position string, message string) {
 	id := ctx.Value("logid")
 	logid := cast.ToString(id)
@@ -0,0 +1,133 @@
+package logx
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/ytf606/golibs/logx/log4go"
+)
+
+// Field 结构化日志字段, 类型化的值一直保留到log4go的writer中编码
+type Field = log4go.Field
+
+// FieldsBuilder 支持结构化字段的MessageBuilder, 未实现时字段以key=value拼接到消息后
+type FieldsBuilder interface {
+	LoggerFields(ctx context.Context, lvl string, tag string, msg string, fields []Field)
+}
+
+const tagKey = "_logx_tag"
+
+func String(key, value string) Field {
+	return Field{Key: key, Type: log4go.StringField, Str: value}
+}
+
+func Int(key string, value int) Field {
+	return Field{Key: key, Type: log4go.IntField, Integer: int64(value)}
+}
+
+func Int64(key string, value int64) Field {
+	return Field{Key: key, Type: log4go.IntField, Integer: value}
+}
+
+func Uint64(key string, value uint64) Field {
+	return Field{Key: key, Type: log4go.UintField, Integer: int64(value)}
+}
+
+func Float64(key string, value float64) Field {
+	return Field{Key: key, Type: log4go.FloatField, Float: value}
+}
+
+func Bool(key string, value bool) Field {
+	f := Field{Key: key, Type: log4go.BoolField}
+	if value {
+		f.Integer = 1
+	}
+	return f
+}
+
+// Duration json格式输出为秒
+func Duration(key string, value time.Duration) Field {
+	return Field{Key: key, Type: log4go.DurationField, Integer: int64(value)}
+}
+
+func Time(key string, value time.Time) Field {
+	return Field{Key: key, Type: log4go.TimeField, Interface: value}
+}
+
+// Err key固定为error, err为nil时值为空
+func Err(err error) Field {
+	f := Field{Key: "error", Type: log4go.ErrorField}
+	if err != nil {
+		f.Str = err.Error()
+	}
+	return f
+}
+
+// Any 其他类型, json格式按json.Marshal编码
+func Any(key string, value interface{}) Field {
+	return Field{Key: key, Type: log4go.AnyField, Interface: value}
+}
+
+// Tag 指定日志tag, 不指定时使用msg作为tag
+func Tag(tag string) Field {
+	return Field{Key: tagKey, Type: log4go.StringField, Str: tag}
+}
+
+func Debug(ctx context.Context, msg string, fields ...Field) {
+	tag, fields := splitTag(msg, fields)
+	if fb, ok := builder.(FieldsBuilder); ok {
+		fb.LoggerFields(ctx, "DEBUG", tag, msg, fields)
+		return
+	}
+	builder.LoggerX(ctx, "DEBUG", tag, fieldsText(msg, fields))
+}
+
+func Info(ctx context.Context, msg string, fields ...Field) {
+	tag, fields := splitTag(msg, fields)
+	if fb, ok := builder.(FieldsBuilder); ok {
+		fb.LoggerFields(ctx, "INFO", tag, msg, fields)
+		return
+	}
+	builder.LoggerX(ctx, "INFO", tag, fieldsText(msg, fields))
+}
+
+func Warn(ctx context.Context, msg string, fields ...Field) {
+	tag, fields := splitTag(msg, fields)
+	if fb, ok := builder.(FieldsBuilder); ok {
+		fb.LoggerFields(ctx, "WARNING", tag, msg, fields)
+		return
+	}
+	builder.LoggerX(ctx, "WARNING", tag, fieldsText(msg, fields))
+}
+
+func Error(ctx context.Context, msg string, fields ...Field) {
+	tag, fields := splitTag(msg, fields)
+	if fb, ok := builder.(FieldsBuilder); ok {
+		fb.LoggerFields(ctx, "ERROR", tag, msg, fields)
+		return
+	}
+	builder.LoggerX(ctx, "ERROR", tag, fieldsText(msg, fields))
+}
+
+// splitTag 取出Tag字段, 调用方直接调用builder以保持与Ix等相同的调用栈深度
+func splitTag(msg string, fields []Field) (string, []Field) {
+	for i, f := range fields {
+		if f.Key == tagKey {
+			return f.Str, append(fields[:i:i], fields[i+1:]...)
+		}
+	}
+	return msg, fields
+}
+
+func fieldsText(msg string, fields []Field) string {
+	var b strings.Builder
+	b.WriteString(msg)
+	for _, f := range fields {
+		b.WriteByte(' ')
+		b.WriteString(f.Key)
+		b.WriteByte('=')
+		b.WriteString(f.Text())
+	}
+	return b.String()
+}
@@ -0,0 +1,68 @@
+package logx
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ytf606/golibs/logx/log4go"
+)
+
+func TestStructuredFields(t *testing.T) {
+	w := &recordWriter{}
+	log4go.Global = log4go.Logger{"test": &log4go.Filter{Level: log4go.DEBUG, LogWriter: w}}
+	defer func() { log4go.Global = log4go.NewDefaultLogger(log4go.DEBUG) }()
+
+	Info(context.Background(), "user login",
+		Tag("[user_login]"),
+		String("uid", "u 1"),
+		Int("age", 18),
+		Duration("cost", 1500*time.Millisecond),
+		Err(errors.New("bad \"pwd\"\n")),
+	)
+	if !assert.Len(t, w.recs, 1) {
+		return
+	}
+	rec := w.recs[0]
+	assert.Equal(t, "[user_login]\tuser login", rec.Message)
+	assert.Len(t, rec.Fields, 4)
+	assert.Equal(t, int64(18), rec.Fields[1].Value())
+	assert.Equal(t, "[user_login]\tuser login uid=\"u 1\" age=18 cost=1.5s error=\"bad \\\"pwd\\\"\\n\"\n",
+		log4go.FormatLogRecord("%M", rec))
+
+	buf := &bytes.Buffer{}
+	for _, f := range rec.Fields {
+		f.AppendJSON(buf)
+		buf.WriteByte(',')
+	}
+	assert.Equal(t, `"uid":"u 1","age":18,"cost":1.500000,"error":"bad \"pwd\"\n",`, buf.String())
+}
+
+func TestCostField(t *testing.T) {
+	w := &recordWriter{}
+	log4go.Global = log4go.Logger{"test": &log4go.Filter{Level: log4go.DEBUG, LogWriter: w}}
+	defer func() { log4go.Global = log4go.NewDefaultLogger(log4go.DEBUG) }()
+
+	ctx := context.WithValue(context.Background(), "start", time.Now())
+	fields := []Field{String("uid", "u1")}
+	Info(ctx, "done", append(fields, Tag("[cost]"))...)
+	Ix(ctx, "[cost]", "done")
+	Ix(context.Background(), "[cost]", "price COST:5")
+	if !assert.Len(t, w.recs, 3) {
+		return
+	}
+	// 结构化字段的耗时追加到副本上
+	assert.Len(t, fields, 1)
+	for _, rec := range w.recs[:2] {
+		last := rec.Fields[len(rec.Fields)-1]
+		assert.Equal(t, log4go.CostField, last.Type)
+		assert.True(t, strings.HasSuffix(rec.Message, " COST:"+last.Str))
+		assert.Contains(t, log4go.FormatLogRecord(log4go.FORMAT_JSON, rec), `"message":"done",`)
+	}
+	assert.Empty(t, w.recs[2].Fields)
+	assert.Contains(t, log4go.FormatLogRecord(log4go.FORMAT_JSON, w.recs[2]), `"message":"price COST:5"`)
+}
@@ -47,11 +47,17 @@ func InitLogWithConfig(config *log4go.LogConfig) {
 func Close() {
 	log4go.Close()
 }
+
+// Dropped 各日志writer因缓冲已满丢弃的日志条数, key为LogConfig.Tag
+func Dropped() map[string]uint64 {
+	return log4go.Dropped()
+}
 func checkLogConfig(config *log4go.LogConfig) {
 	if _, ok := logutils.LevelMap[config.Level]; ok {
 		if logutils.LevelMap[config.Level] < logutils.SortLevel {
 			logutils.SortLevel = logutils.LevelMap[config.Level]
 			logutils.Level = config.Level
+			logutils.SetLevel(config.Level)
 		}
 	}
 	paths := strings.Split(config.LogPath, "/")
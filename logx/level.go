@@ -0,0 +1,181 @@
+package logx
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ytf606/golibs/logx/log4go"
+	"github.com/ytf606/golibs/logx/logutils"
+)
+
+// LevelInfo 当前生效的日志级别
+type LevelInfo struct {
+	// Level 全局级别, 低于该级别的日志不会输出
+	Level string `json:"level"`
+	// Tags 各日志writer的级别, key为LogConfig.Tag
+	Tags map[string]string `json:"tags"`
+	// Expires 临时调整的恢复时间, key为tag, 全局级别的key为空字符串
+	Expires map[string]time.Time `json:"expires,omitempty"`
+}
+
+// levelOrder 由低到高, 用于log4go.Level转回名称
+var levelOrder = []string{"FINEST", "FINE", "DEBUG", "INFO", "WARNING", "ERROR", "TRACE", "CRITICAL"}
+
+// levels 运行时调整的级别, 未调整的使用InitLogWithConfig配置的级别
+var levels = struct {
+	sync.Mutex
+	global  string
+	tags    map[string]string
+	timers  map[string]*time.Timer
+	expires map[string]time.Time
+}{
+	tags:    make(map[string]string),
+	timers:  make(map[string]*time.Timer),
+	expires: make(map[string]time.Time),
+}
+
+// Levels 当前生效的全局级别和各writer的级别
+func Levels() LevelInfo {
+	levels.Lock()
+	defer levels.Unlock()
+	info := LevelInfo{
+		Level: logutils.Level,
+		Tags:  make(map[string]string),
+	}
+	if levels.global != "" {
+		info.Level = levels.global
+	}
+	for tag, lvl := range log4go.FilterLevels() {
+		info.Tags[tag] = levelName(lvl)
+	}
+	if len(levels.expires) > 0 {
+		info.Expires = make(map[string]time.Time, len(levels.expires))
+		for key, t := range levels.expires {
+			info.Expires[key] = t
+		}
+	}
+	return info
+}
+
+// SetLevel 运行时调整全局级别, ttl大于0时到期自动恢复
+// 同时作用于未单独调整且配置级别不高于配置的全局级别的writer, error、audit等高级别writer保持各自的级别
+func SetLevel(lvl string, ttl time.Duration) error {
+	lvl, err := checkLevel(lvl)
+	if err != nil {
+		return err
+	}
+	levels.Lock()
+	defer levels.Unlock()
+	levels.global = lvl
+	scheduleRevert("", ttl)
+	applyLevels()
+	W("[logx_level]", "log level set to %s, ttl:%s", lvl, ttl)
+	return nil
+}
+
+// SetTagLevel 运行时调整某个writer(LogConfig.Tag)的级别, ttl大于0时到期自动恢复
+func SetTagLevel(tag, lvl string, ttl time.Duration) error {
+	lvl, err := checkLevel(lvl)
+	if err != nil {
+		return err
+	}
+	if _, ok := log4go.Global[tag]; !ok {
+		return fmt.Errorf("log tag %s not found", tag)
+	}
+	levels.Lock()
+	defer levels.Unlock()
+	levels.tags[tag] = lvl
+	scheduleRevert(tag, ttl)
+	applyLevels()
+	W("[logx_level]", "log level of tag %s set to %s, ttl:%s", tag, lvl, ttl)
+	return nil
+}
+
+// ResetLevel 撤销所有运行时调整, 恢复配置的级别
+func ResetLevel() {
+	levels.Lock()
+	defer levels.Unlock()
+	for key, t := range levels.timers {
+		t.Stop()
+		delete(levels.timers, key)
+	}
+	levels.global = ""
+	levels.tags = make(map[string]string)
+	levels.expires = make(map[string]time.Time)
+	applyLevels()
+}
+
+func checkLevel(lvl string) (string, error) {
+	lvl = strings.ToUpper(strings.TrimSpace(lvl))
+	if _, ok := logutils.LevelMap[lvl]; !ok {
+		return "", fmt.Errorf("unknown log level %s", lvl)
+	}
+	return lvl, nil
+}
+
+// scheduleRevert 需持有levels锁, 重复调整时以最后一次的ttl为准
+func scheduleRevert(key string, ttl time.Duration) {
+	if t, ok := levels.timers[key]; ok {
+		t.Stop()
+		delete(levels.timers, key)
+		delete(levels.expires, key)
+	}
+	if ttl <= 0 {
+		return
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(ttl, func() {
+		levels.Lock()
+		defer levels.Unlock()
+		// 已被新的调整取代
+		if levels.timers[key] != timer {
+			return
+		}
+		delete(levels.timers, key)
+		delete(levels.expires, key)
+		if key == "" {
+			levels.global = ""
+		} else {
+			delete(levels.tags, key)
+		}
+		applyLevels()
+		W("[logx_level]", "log level reverted tag:%s, level:%s", key, logutils.GetLevel())
+	})
+	levels.timers[key] = timer
+	levels.expires[key] = time.Now().Add(ttl)
+}
+
+// applyLevels 需持有levels锁, 全局级别取调整后各级别中最低的, 以免拦住单独调低的writer
+func applyLevels() {
+	global := logutils.Level
+	if levels.global != "" {
+		global = levels.global
+	}
+	gate := global
+	for _, lvl := range levels.tags {
+		if logutils.LevelMap[lvl] < logutils.LevelMap[gate] {
+			gate = lvl
+		}
+	}
+	logutils.SetLevel(gate)
+
+	base := logutils.LevelMap[logutils.Level]
+	for tag, filt := range log4go.Global {
+		if lvl, ok := levels.tags[tag]; ok {
+			log4go.SetFilterLevel(tag, logutils.LevelMap[lvl])
+		} else if levels.global != "" && filt.Level <= base {
+			log4go.SetFilterLevel(tag, logutils.LevelMap[levels.global])
+		} else {
+			log4go.ResetFilterLevel(tag)
+		}
+	}
+}
+
+func levelName(lvl log4go.Level) string {
+	if int(lvl) >= 0 && int(lvl) < len(levelOrder) {
+		return levelOrder[lvl]
+	}
+	return lvl.String()
+}
@@ -0,0 +1,87 @@
+package logx
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ytf606/golibs/logx/log4go"
+	"github.com/ytf606/golibs/logx/logutils"
+)
+
+func TestSetLevel(t *testing.T) {
+	w, errw := &recordWriter{}, &recordWriter{}
+	log4go.Global = log4go.Logger{
+		"default": &log4go.Filter{Level: log4go.INFO, LogWriter: w},
+		"audit":   &log4go.Filter{Level: log4go.ERROR, LogWriter: errw},
+	}
+	logutils.Inited, logutils.Level = true, "INFO"
+	logutils.SetLevel("INFO")
+	defer func() {
+		ResetLevel()
+		logutils.Inited, logutils.Level = false, "ERROR"
+		logutils.SetLevel("ERROR")
+		log4go.Global = log4go.NewDefaultLogger(log4go.DEBUG)
+	}()
+
+	D("[test]", "hidden")
+	assert.Equal(t, 0, w.count("[test]"))
+
+	assert.Error(t, SetLevel("VERBOSE", 0))
+	assert.Error(t, SetTagLevel("missing", "DEBUG", 0))
+
+	// 级别高于原全局级别的writer不随全局级别调低
+	assert.NoError(t, SetLevel("debug", 50*time.Millisecond))
+	D("[test]", "shown")
+	assert.Equal(t, 1, w.count("[test]"))
+	assert.Equal(t, 0, errw.count("[test]"))
+	E("[test_err]", "error")
+	assert.Equal(t, 1, errw.count("[test_err]"))
+	info := Levels()
+	assert.Equal(t, "DEBUG", info.Level)
+	assert.Equal(t, map[string]string{"default": "DEBUG", "audit": "ERROR"}, info.Tags)
+	assert.Contains(t, info.Expires, "")
+
+	assert.NoError(t, SetTagLevel("audit", "WARNING", 0))
+	assert.Equal(t, "WARNING", Levels().Tags["audit"])
+
+	// 全局级别到期恢复, 单独调整的tag保持
+	time.Sleep(100 * time.Millisecond)
+	D("[test]", "hidden")
+	assert.Equal(t, 1, w.count("[test]"))
+	info = Levels()
+	assert.Equal(t, "INFO", info.Level)
+	assert.Equal(t, map[string]string{"default": "INFO", "audit": "WARNING"}, info.Tags)
+	assert.Empty(t, info.Expires)
+}
+
+func TestCtxLevel(t *testing.T) {
+	w, errw := &recordWriter{}, &recordWriter{}
+	log4go.Global = log4go.Logger{
+		"default": &log4go.Filter{Level: log4go.INFO, LogWriter: w},
+		"error":   &log4go.Filter{Level: log4go.ERROR, LogWriter: errw},
+	}
+	logutils.Inited, logutils.Level = true, "INFO"
+	logutils.SetLevel("INFO")
+	defer func() {
+		logutils.Inited, logutils.Level = false, "ERROR"
+		logutils.SetLevel("ERROR")
+		log4go.Global = log4go.NewDefaultLogger(log4go.DEBUG)
+	}()
+
+	Dx(context.Background(), "[test]", "hidden")
+	assert.Equal(t, 0, w.count("[test]"))
+
+	// 单个请求调低级别, 只写入级别不高于全局级别的writer
+	ctx := context.WithValue(context.Background(), logutils.CtxLevelKey, "DEBUG")
+	Dx(ctx, "[test]", "shown")
+	Debug(ctx, "shown", Tag("[test]"), Int("n", 1))
+	assert.Equal(t, 2, w.count("[test]"))
+	assert.Equal(t, 0, errw.count("[test]"))
+
+	// 单个请求调高级别
+	ctx = context.WithValue(context.Background(), logutils.CtxLevelKey, "ERROR")
+	Ix(ctx, "[test]", "hidden")
+	assert.Equal(t, 2, w.count("[test]"))
+}
@@ -64,7 +64,7 @@ type LogConfig struct {
 	Level string
 	//日志标签 多日志时使用
 	Tag string
-	//日志格式
+	//日志格式, FORMAT_JSON("json")时每行输出一个json对象
 	Format string
 	//最大行数切割
 	RotateLines string
@@ -80,6 +80,10 @@ type LogConfig struct {
 	Console bool
 	//SuffixEnable环境变量
 	SuffixEnv string
+	//写入缓冲的日志条数, 默认LogBufferLength
+	BufferSize string
+	//缓冲满时的策略: block, drop-newest(默认), drop-oldest, spill
+	Overflow string
 }
 
 func (log *LogConfig) SetConfigMap(conf map[string]string) {
@@ -107,6 +111,10 @@ func (log *LogConfig) SetConfigMap(conf map[string]string) {
 			log.Console = (v == "true")
 		case "SuffixEnv":
 			log.SuffixEnv = v
+		case "BufferSize":
+			log.BufferSize = v
+		case "Overflow":
+			log.Overflow = v
 		}
 	}
 }
@@ -144,10 +152,19 @@ func (log Logger) LoadLogConfig(config *LogConfig) {
 	hourly := config.RotateHourly
 	rotate := config.Rotate
 	retention, _ := strconv.Atoi(config.Retention)
+	bufsize, _ := strconv.Atoi(strings.Trim(config.BufferSize, " \r\n"))
+	policy, ok := OverflowDropNewest, true
+	if config.Overflow != "" {
+		if policy, ok = ParseOverflowPolicy(config.Overflow); !ok {
+			fmt.Fprintf(os.Stderr, "LoadLogConfig: Error: Overflow has unknown value %s\n", config.Overflow)
+			os.Exit(1)
+		}
+	}
 
 	suffix := getRandSuffixEnv(config.SuffixEnv)
 	file = fileRandRename(file, suffix)
-	flw := NewFileLogTraceWriter(file, rotate)
+	flw := NewFileLogTraceWriterWithBuffer(file, rotate, bufsize)
+	flw.SetOverflow(policy)
 	flw.SetFormat(format)
 	flw.SetRotateLines(maxlines)
 	flw.SetRotateSize(maxsize)
@@ -156,6 +173,10 @@ func (log Logger) LoadLogConfig(config *LogConfig) {
 	log[config.Tag] = &Filter{lvl, flw}
 	if config.Console {
 		filt, _ := xmlToConsoleLogWriter("defaultConsole", []xmlProperty{}, true)
+		// json格式同样适用于控制台, 便于容器内采集
+		if format == FORMAT_JSON {
+			filt.SetFormat(format)
+		}
 		log["Console"] = &Filter{lvl, filt}
 	}
 	return
@@ -319,6 +340,8 @@ func xmlToFileLogWriter(filename string, props []xmlProperty, enabled bool) (*Fi
 	maxsize := 0
 	daily := false
 	rotate := false
+	bufsize := 0
+	overflow := ""
 
 	// Parse properties
 	for _, prop := range props {
@@ -335,6 +358,10 @@ func xmlToFileLogWriter(filename string, props []xmlProperty, enabled bool) (*Fi
 			daily = strings.Trim(prop.Value, " \r\n") != "false"
 		case "rotate":
 			rotate = strings.Trim(prop.Value, " \r\n") != "false"
+		case "buffersize":
+			bufsize, _ = strconv.Atoi(strings.Trim(prop.Value, " \r\n"))
+		case "overflow":
+			overflow = strings.Trim(prop.Value, " \r\n")
 		default:
 			fmt.Fprintf(os.Stderr, "LoadConfiguration: Warning: Unknown property \"%s\" for file filter in %s\n", prop.Name, filename)
 		}
@@ -345,13 +372,20 @@ func xmlToFileLogWriter(filename string, props []xmlProperty, enabled bool) (*Fi
 		fmt.Fprintf(os.Stderr, "LoadConfiguration: Error: Required property \"%s\" for file filter missing in %s\n", "filename", filename)
 		return nil, false
 	}
+	policy, ok := xmlToOverflowPolicy(filename, overflow)
+	if !ok {
+		return nil, false
+	}
 
 	// If it's disabled, we're just checking syntax
 	if !enabled {
 		return nil, true
 	}
 
-	flw := NewFileLogWriter(file, rotate)
+	flw := NewFileLogWriterWithBuffer(file, rotate, bufsize)
+	if overflow != "" {
+		flw.SetOverflow(policy)
+	}
 	flw.SetFormat(format)
 	flw.SetRotateLines(maxlines)
 	flw.SetRotateSize(maxsize)
@@ -365,6 +399,8 @@ func xmlToFileLogWriter2(filename string, props []xmlProperty, enabled bool) (*F
 	maxsize := 0
 	daily := false
 	rotate := false
+	bufsize := 0
+	overflow := ""
 
 	// Parse properties
 	for _, prop := range props {
@@ -381,6 +417,10 @@ func xmlToFileLogWriter2(filename string, props []xmlProperty, enabled bool) (*F
 			daily = strings.Trim(prop.Value, " \r\n") != "false"
 		case "rotate":
 			rotate = strings.Trim(prop.Value, " \r\n") != "false"
+		case "buffersize":
+			bufsize, _ = strconv.Atoi(strings.Trim(prop.Value, " \r\n"))
+		case "overflow":
+			overflow = strings.Trim(prop.Value, " \r\n")
 		default:
 			fmt.Fprintf(os.Stderr, "LoadConfiguration: Warning: Unknown property \"%s\" for file filter in %s\n", prop.Name, filename)
 		}
@@ -391,13 +431,20 @@ func xmlToFileLogWriter2(filename string, props []xmlProperty, enabled bool) (*F
 		fmt.Fprintf(os.Stderr, "LoadConfiguration: Error: Required property \"%s\" for file filter missing in %s\n", "filename", filename)
 		return nil, false
 	}
+	policy, ok := xmlToOverflowPolicy(filename, overflow)
+	if !ok {
+		return nil, false
+	}
 
 	// If it's disabled, we're just checking syntax
 	if !enabled {
 		return nil, true
 	}
 
-	flw := NewFileLog2Writer(file, rotate)
+	flw := NewFileLog2WriterWithBuffer(file, rotate, bufsize)
+	if overflow != "" {
+		flw.SetOverflow(policy)
+	}
 	flw.SetFormat(format)
 	flw.SetRotateLines(maxlines)
 	flw.SetRotateSize(maxsize)
@@ -414,6 +461,8 @@ func xmlToFileLogWriterTrace(filename string, props []xmlProperty, enabled bool)
 	rotate := false
 	retention := 0
 	suffixenv := ""
+	bufsize := 0
+	overflow := ""
 
 	// Parse properties
 	for _, prop := range props {
@@ -434,6 +483,10 @@ func xmlToFileLogWriterTrace(filename string, props []xmlProperty, enabled bool)
 			retention, _ = strconv.Atoi(strings.Trim(prop.Value, " \r\n"))
 		case "suffixenv":
 			suffixenv = strings.Trim(prop.Value, " \r\n")
+		case "buffersize":
+			bufsize, _ = strconv.Atoi(strings.Trim(prop.Value, " \r\n"))
+		case "overflow":
+			overflow = strings.Trim(prop.Value, " \r\n")
 		default:
 			fmt.Fprintf(os.Stderr, "LoadConfiguration: Warning: Unknown property \"%s\" for file filter in %s\n", prop.Name, filename)
 		}
@@ -444,6 +497,10 @@ func xmlToFileLogWriterTrace(filename string, props []xmlProperty, enabled bool)
 		fmt.Fprintf(os.Stderr, "LoadConfiguration: Error: Required property \"%s\" for file filter missing in %s\n", "filename", filename)
 		return nil, false
 	}
+	policy, ok := xmlToOverflowPolicy(filename, overflow)
+	if !ok {
+		return nil, false
+	}
 
 	// If it's disabled, we're just checking syntax
 	if !enabled {
@@ -453,7 +510,10 @@ func xmlToFileLogWriterTrace(filename string, props []xmlProperty, enabled bool)
 	file = tryUpdateFilename(file)
 	suffix := getRandSuffixEnv(suffixenv)
 	file = fileRandRename(file, suffix)
-	flw := NewFileLogTraceWriter(file, rotate)
+	flw := NewFileLogTraceWriterWithBuffer(file, rotate, bufsize)
+	if overflow != "" {
+		flw.SetOverflow(policy)
+	}
 	flw.SetFormat(format)
 	flw.SetRotateLines(maxlines)
 	flw.SetRotateSize(maxsize)
@@ -462,6 +522,18 @@ func xmlToFileLogWriterTrace(filename string, props []xmlProperty, enabled bool)
 	return flw, true
 }
 
+// xmlToOverflowPolicy 未配置时返回ok, 由writer使用默认策略
+func xmlToOverflowPolicy(filename, overflow string) (OverflowPolicy, bool) {
+	if overflow == "" {
+		return OverflowBlock, true
+	}
+	policy, ok := ParseOverflowPolicy(overflow)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "LoadConfiguration: Error: Property \"%s\" for file filter has unknown value in %s: %s\n", "overflow", filename, overflow)
+	}
+	return policy, ok
+}
+
 func tryUpdateFilename(filename string) string {
 	exePath, err := os.Executable()
 	if err != nil {
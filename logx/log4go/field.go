@@ -0,0 +1,213 @@
+package log4go
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+// FieldType 结构化字段的值类型
+type FieldType uint8
+
+const (
+	StringField FieldType = iota
+	IntField
+	UintField
+	FloatField
+	BoolField
+	DurationField
+	TimeField
+	ErrorField
+	AnyField
+	// CostField builder追加到消息末尾的耗时(" COST:x"), Str为x
+	// 文本格式已在消息中, 不重复输出; json格式据此从消息中拆出cost字段
+	CostField
+)
+
+// NewCostField builder在消息末尾追加" COST:"+cost时同时附带该字段
+func NewCostField(cost string) Field {
+	return Field{Key: "cost", Type: CostField, Str: cost}
+}
+
+// Field 结构化日志字段, 按类型保存原始值, 由各writer在输出时编码
+type Field struct {
+	Key       string
+	Type      FieldType
+	Integer   int64
+	Float     float64
+	Str       string
+	Interface interface{}
+}
+
+// Value 字段的原始值
+func (f Field) Value() interface{} {
+	switch f.Type {
+	case StringField, ErrorField, CostField:
+		return f.Str
+	case IntField:
+		return f.Integer
+	case UintField:
+		return uint64(f.Integer)
+	case FloatField:
+		return f.Float
+	case BoolField:
+		return f.Integer == 1
+	case DurationField:
+		return time.Duration(f.Integer)
+	case TimeField:
+		return f.Interface
+	}
+	return f.Interface
+}
+
+// Text 文本格式下的值, 含空白或引号的字符串加引号
+func (f Field) Text() string {
+	switch f.Type {
+	case StringField, ErrorField, CostField:
+		return quoteIfNeeded(f.Str)
+	case IntField:
+		return strconv.FormatInt(f.Integer, 10)
+	case UintField:
+		return strconv.FormatUint(uint64(f.Integer), 10)
+	case FloatField:
+		return strconv.FormatFloat(f.Float, 'f', -1, 64)
+	case BoolField:
+		return strconv.FormatBool(f.Integer == 1)
+	case DurationField:
+		return time.Duration(f.Integer).String()
+	case TimeField:
+		if t, ok := f.Interface.(time.Time); ok {
+			return t.Format(time.RFC3339Nano)
+		}
+	}
+	return quoteIfNeeded(fmt.Sprintf("%+v", f.Interface))
+}
+
+// AppendJSON 以"key":value的形式写入buf, 数值类型不加引号, duration输出为秒
+func (f Field) AppendJSON(buf *bytes.Buffer) {
+	appendJSONString(buf, f.Key)
+	buf.WriteByte(':')
+	switch f.Type {
+	case StringField, ErrorField, CostField:
+		appendJSONString(buf, f.Str)
+	case IntField:
+		buf.WriteString(strconv.FormatInt(f.Integer, 10))
+	case UintField:
+		buf.WriteString(strconv.FormatUint(uint64(f.Integer), 10))
+	case FloatField:
+		if math.IsNaN(f.Float) || math.IsInf(f.Float, 0) {
+			appendJSONString(buf, strconv.FormatFloat(f.Float, 'f', -1, 64))
+		} else {
+			buf.WriteString(strconv.FormatFloat(f.Float, 'f', -1, 64))
+		}
+	case BoolField:
+		buf.WriteString(strconv.FormatBool(f.Integer == 1))
+	case DurationField:
+		buf.WriteString(strconv.FormatFloat(time.Duration(f.Integer).Seconds(), 'f', 6, 64))
+	case TimeField:
+		appendJSONString(buf, f.Text())
+	default:
+		data, err := json.Marshal(f.Interface)
+		if err != nil {
+			appendJSONString(buf, fmt.Sprintf("%+v", f.Interface))
+			return
+		}
+		buf.Write(data)
+	}
+}
+
+func quoteIfNeeded(s string) string {
+	for _, r := range s {
+		if r <= ' ' || r == '"' || r == '=' || r == utf8.RuneError {
+			return strconv.Quote(s)
+		}
+	}
+	if s == "" {
+		return `""`
+	}
+	return s
+}
+
+const hexDigits = "0123456789abcdef"
+
+// appendJSONString 按json规则转义字符串
+func appendJSONString(buf *bytes.Buffer, s string) {
+	buf.WriteByte('"')
+	start := 0
+	for i := 0; i < len(s); {
+		b := s[i]
+		if b < utf8.RuneSelf {
+			if b >= 0x20 && b != '"' && b != '\\' {
+				i++
+				continue
+			}
+			buf.WriteString(s[start:i])
+			switch b {
+			case '"', '\\':
+				buf.WriteByte('\\')
+				buf.WriteByte(b)
+			case '\n':
+				buf.WriteString(`\n`)
+			case '\r':
+				buf.WriteString(`\r`)
+			case '\t':
+				buf.WriteString(`\t`)
+			default:
+				buf.WriteString(`\u00`)
+				buf.WriteByte(hexDigits[b>>4])
+				buf.WriteByte(hexDigits[b&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			buf.WriteString(s[start:i])
+			buf.WriteString(`\ufffd`)
+			i += size
+			start = i
+			continue
+		}
+		// json.Marshal同样转义的行分隔符
+		if r == '\u2028' || r == '\u2029' {
+			buf.WriteString(s[start:i])
+			buf.WriteString(`\u202`)
+			buf.WriteByte(hexDigits[r&0xF])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	buf.WriteString(s[start:])
+	buf.WriteByte('"')
+}
+
+// appendFieldsText 文本格式下追加在消息之后, 形如 key=value
+func appendFieldsText(buf *bytes.Buffer, fields []Field) {
+	for _, f := range fields {
+		if f.Type == CostField {
+			continue
+		}
+		buf.WriteByte(' ')
+		buf.WriteString(f.Key)
+		buf.WriteByte('=')
+		buf.WriteString(f.Text())
+	}
+}
+
+// appendFieldsJSON 每个字段后带逗号, 用于json格式中间位置
+func appendFieldsJSON(buf *bytes.Buffer, fields []Field) {
+	for _, f := range fields {
+		if f.Type == CostField {
+			continue
+		}
+		f.AppendJSON(buf)
+		buf.WriteByte(',')
+	}
+}
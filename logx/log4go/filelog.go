@@ -38,16 +38,20 @@ type FileLogWriter struct {
 	// Keep old logfiles (.001, .002, etc)
 	rotate    bool
 	maxbackup int
+
+	// What to do when the buffer is full
+	overflow
 }
 
 // This is the FileLogWriter's output method
 func (w *FileLogWriter) LogWrite(rec *LogRecord) {
-	w.rec <- rec
+	w.send(w.rec, rec)
 }
 
 func (w *FileLogWriter) Close() {
 	close(w.rec)
 	w.file.Sync()
+	w.closeSpill()
 }
 
 // NewFileLogWriter creates a new LogWriter which writes to the given file and
@@ -60,14 +64,26 @@ func (w *FileLogWriter) Close() {
 // The standard log-line format is:
 //   [%D %T] [%L] (%S) %M
 func NewFileLogWriter(fname string, rotate bool) *FileLogWriter {
+	return NewFileLogWriterWithBuffer(fname, rotate, LogBufferLength)
+}
+
+// NewFileLogWriterWithBuffer is NewFileLogWriter with a buffer of size records.
+// When the buffer is full the writer blocks, see SetOverflow.
+func NewFileLogWriterWithBuffer(fname string, rotate bool, size int) *FileLogWriter {
+	if size <= 0 {
+		size = LogBufferLength
+	}
 	w := &FileLogWriter{
-		rec:       make(chan *LogRecord, LogBufferLength),
+		rec:       make(chan *LogRecord, size),
 		rot:       make(chan bool),
 		filename:  fname,
 		format:    "[%D %T] [%L] (%S) %M",
 		rotate:    rotate,
 		maxbackup: 999,
 	}
+	w.encode = func(rec *LogRecord) []byte {
+		return []byte(FormatLogRecord(w.format, rec))
+	}
 
 	// open the file for the first time
 	if err := w.intRotate(); err != nil {
@@ -75,8 +91,10 @@ func NewFileLogWriter(fname string, rotate bool) *FileLogWriter {
 		return nil
 	}
 
+	tick := time.NewTicker(time.Second)
 	go func() {
 		defer func() {
+			tick.Stop()
 			if w.file != nil {
 				fmt.Fprint(w.file, FormatLogRecord(w.trailer, &LogRecord{Created: time.Now()}))
 				w.file.Close()
@@ -85,6 +103,12 @@ func NewFileLogWriter(fname string, rotate bool) *FileLogWriter {
 
 		for {
 			select {
+			case now := <-tick.C:
+				// Report dropped records even if nothing else is logged
+				if warn := w.warnRecord(now); warn != nil {
+					n, _ := fmt.Fprint(w.file, FormatLogRecord(w.format, warn))
+					w.maxsize_cursize += n
+				}
 			case <-w.rot:
 				if err := w.intRotate(); err != nil {
 					fmt.Fprintf(os.Stderr, "FileLogWriter(%q): %s\n", w.filename, err)
@@ -104,6 +128,11 @@ func NewFileLogWriter(fname string, rotate bool) *FileLogWriter {
 					}
 				}
 
+				if warn := w.warnRecord(now); warn != nil {
+					n, _ := fmt.Fprint(w.file, FormatLogRecord(w.format, warn))
+					w.maxsize_cursize += n
+				}
+
 				// Perform the write
 				n, err := fmt.Fprint(w.file, FormatLogRecord(w.format, rec))
 				if err != nil {
@@ -194,7 +223,7 @@ func (w *FileLogWriter) intRotate() error {
 }
 
 // Set the logging format (chainable).  Must be called before the first log
-// message is written.
+// message is written.  FORMAT_JSON writes one json object per line.
 func (w *FileLogWriter) SetFormat(format string) *FileLogWriter {
 	w.format = format
 	return w
@@ -252,6 +281,15 @@ func (w *FileLogWriter) SetRotate(rotate bool) *FileLogWriter {
 	return w
 }
 
+// SetOverflow sets what LogWrite does when the buffer is full (chainable).
+// Must be called before the first log message is written.  OverflowSpill
+// writes to fname.spill.
+func (w *FileLogWriter) SetOverflow(policy OverflowPolicy) *FileLogWriter {
+	w.policy = policy
+	w.spillName = w.filename + ".spill"
+	return w
+}
+
 // NewXMLLogWriter is a utility method for creating a FileLogWriter set up to
 // output XML record log messages instead of line-based ones.
 func NewXMLLogWriter(fname string, rotate bool) *FileLogWriter {
@@ -44,6 +44,9 @@ type FileLog2Writer struct {
 	// Keep old logfiles (.001, .002, etc)
 	rotate    bool
 	maxbackup int
+
+	// What to do when the buffer is full
+	overflow
 }
 
 var g_levelMapper = map[Level]string{
@@ -59,7 +62,7 @@ var g_levelMapper = map[Level]string{
 
 // This is the FileLog2Writer's output method
 func (w *FileLog2Writer) LogWrite(rec *LogRecord) {
-	w.rec <- rec
+	w.send(w.rec, rec)
 }
 
 func (w *FileLog2Writer) Close() {
@@ -71,6 +74,7 @@ func (w *FileLog2Writer) Close() {
 	close(w.out)
 	w.file.Close()
 	w.file.Sync()
+	w.closeSpill()
 }
 
 // NewFileLog2Writer creates a new LogWriter which writes to the given file and
@@ -83,8 +87,17 @@ func (w *FileLog2Writer) Close() {
 // The standard log-line format is:
 //   [%D %T] [%L] (%S) %M
 func NewFileLog2Writer(fname string, rotate bool) *FileLog2Writer {
+	return NewFileLog2WriterWithBuffer(fname, rotate, LogBufferLength)
+}
+
+// NewFileLog2WriterWithBuffer is NewFileLog2Writer with a buffer of size records.
+// When the buffer is full the writer blocks, see SetOverflow.
+func NewFileLog2WriterWithBuffer(fname string, rotate bool, size int) *FileLog2Writer {
+	if size <= 0 {
+		size = LogBufferLength
+	}
 	w := &FileLog2Writer{
-		rec:       make(chan *LogRecord, LogBufferLength),
+		rec:       make(chan *LogRecord, size),
 		out:       make(chan *LogBuffer, LogBufferLength),
 		rot:       make(chan bool),
 		filename:  fname,
@@ -93,6 +106,13 @@ func NewFileLog2Writer(fname string, rotate bool) *FileLog2Writer {
 		rotate:    rotate,
 		maxbackup: 999,
 	}
+	w.encode = func(rec *LogRecord) []byte {
+		lb := bufferPool.Get().(*LogBuffer)
+		defer bufferPool.Put(lb)
+		lb.buf.Reset()
+		w.encodeTo(lb, rec)
+		return append([]byte(nil), lb.buf.Bytes()...)
+	}
 
 	// open the file for the first time
 	if err := w.intRotate(); err != nil {
@@ -100,8 +120,10 @@ func NewFileLog2Writer(fname string, rotate bool) *FileLog2Writer {
 		return nil
 	}
 
+	tick := time.NewTicker(time.Second)
 	go func() {
 		defer func() {
+			tick.Stop()
 			if w.file != nil {
 				fmt.Fprint(w.file, FormatLogRecord(w.trailer, &LogRecord{Created: time.Now()}))
 			}
@@ -109,6 +131,17 @@ func NewFileLog2Writer(fname string, rotate bool) *FileLog2Writer {
 
 		for {
 			select {
+			case now := <-tick.C:
+				// Report dropped records even if nothing else is logged
+				if warn := w.warnRecord(now); warn != nil {
+					if w.logBuffer == nil {
+						w.logBuffer = bufferPool.Get().(*LogBuffer)
+						w.logBuffer.init()
+					}
+					w.encodeTo(w.logBuffer, warn)
+					w.out <- w.logBuffer
+					w.logBuffer = nil
+				}
 			case rec, ok := <-w.rec:
 				if !ok {
 					return
@@ -136,7 +169,10 @@ func NewFileLog2Writer(fname string, rotate bool) *FileLog2Writer {
 					w.logBuffer.init()
 				}
 
-				w.logBuffer.Encode(w.pieces, rec)
+				if warn := w.warnRecord(now); warn != nil {
+					w.encodeTo(w.logBuffer, warn)
+				}
+				w.encodeTo(w.logBuffer, rec)
 				if len(w.rec) == 0 || w.logBuffer.Flush() {
 					w.out <- w.logBuffer
 					w.logBuffer = nil
@@ -293,6 +329,7 @@ func (lb *LogBuffer) Encode(pieces [][]byte, rec *LogRecord) error {
 				} else {
 					lb.buf.WriteString(`"info":"",`)
 				}
+				appendFieldsJSON(lb.buf, rec.Fields)
 				// no more fields
 				lb.buf.WriteString(`"cost":`)
 				lb.buf.WriteString(costValue)
@@ -316,6 +353,14 @@ func (lb *LogBuffer) Encode(pieces [][]byte, rec *LogRecord) error {
 	return nil
 }
 
+func (w *FileLog2Writer) encodeTo(lb *LogBuffer, rec *LogRecord) {
+	if w.format == FORMAT_JSON {
+		EncodeJSONRecord(lb.buf, rec)
+	} else {
+		lb.Encode(w.pieces, rec)
+	}
+}
+
 // Request that the logs rotate
 func (w *FileLog2Writer) Rotate() {
 	w.rot <- true
@@ -389,13 +434,22 @@ func (w *FileLog2Writer) intRotate() error {
 }
 
 // Set the logging format (chainable).  Must be called before the first log
-// message is written.
+// message is written.  FORMAT_JSON writes one json object per line.
 func (w *FileLog2Writer) SetFormat(format string) *FileLog2Writer {
 	w.format = format
 	w.pieces = bytes.Split([]byte(format), []byte{'%'})
 	return w
 }
 
+// SetOverflow sets what LogWrite does when the buffer is full (chainable).
+// Must be called before the first log message is written.  OverflowSpill
+// writes to fname.spill.
+func (w *FileLog2Writer) SetOverflow(policy OverflowPolicy) *FileLog2Writer {
+	w.policy = policy
+	w.spillName = w.filename + ".spill"
+	return w
+}
+
 // Set the logfile header and footer (chainable).  Must be called before the first log
 // message is written.  These are formatted similar to the FormatLogRecord (e.g.
 // you can use %D and %T in your header/footer for date and time).
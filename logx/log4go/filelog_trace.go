@@ -46,16 +46,16 @@ type FileLogTraceWriter struct {
 	rotate    bool
 	maxbackup int
 
+	// What to do when the buffer is full
+	overflow
+
 	// log Retention
 	retention int
 }
 
 // This is the FileLog2Writer's output method
 func (w *FileLogTraceWriter) LogWrite(rec *LogRecord) {
-	select {
-	case w.rec <- rec:
-	default:
-	}
+	w.send(w.rec, rec)
 }
 
 func (w *FileLogTraceWriter) Close() {
@@ -67,6 +67,7 @@ func (w *FileLogTraceWriter) Close() {
 	close(w.out)
 	w.file.Close()
 	w.file.Sync()
+	w.closeSpill()
 }
 
 // NewFileLogTraceWriter creates a new LogWriter which writes to the given file and
@@ -79,8 +80,17 @@ func (w *FileLogTraceWriter) Close() {
 // The standard log-line format is:
 //   [%D %T] [%L] (%S) %M
 func NewFileLogTraceWriter(fname string, rotate bool) *FileLogTraceWriter {
+	return NewFileLogTraceWriterWithBuffer(fname, rotate, LogBufferLength)
+}
+
+// NewFileLogTraceWriterWithBuffer is NewFileLogTraceWriter with a buffer of size records.
+// When the buffer is full the writer drops the newest record, see SetOverflow.
+func NewFileLogTraceWriterWithBuffer(fname string, rotate bool, size int) *FileLogTraceWriter {
+	if size <= 0 {
+		size = LogBufferLength
+	}
 	w := &FileLogTraceWriter{
-		rec:       make(chan *LogRecord, LogBufferLength),
+		rec:       make(chan *LogRecord, size),
 		out:       make(chan *TraceLogBuffer, LogBufferLength),
 		rot:       make(chan bool),
 		filename:  fname,
@@ -88,6 +98,14 @@ func NewFileLogTraceWriter(fname string, rotate bool) *FileLogTraceWriter {
 		pieces:    bytes.Split([]byte("[%G] [%L] (%S) %M"), []byte{'%'}),
 		rotate:    rotate,
 		maxbackup: 999,
+		overflow:  overflow{policy: OverflowDropNewest},
+	}
+	w.encode = func(rec *LogRecord) []byte {
+		lb := g_traceBufferPool.Get().(*TraceLogBuffer)
+		defer g_traceBufferPool.Put(lb)
+		lb.buf.Reset()
+		w.encodeTo(lb, rec)
+		return append([]byte(nil), lb.buf.Bytes()...)
 	}
 
 	// open the file for the first time
@@ -96,18 +114,37 @@ func NewFileLogTraceWriter(fname string, rotate bool) *FileLogTraceWriter {
 		return nil
 	}
 
+	tick := time.NewTicker(time.Second)
 	go func() {
 		defer func() {
+			tick.Stop()
 			if w.file != nil {
 				fmt.Fprint(w.file, FormatLogRecord(w.trailer, &LogRecord{Created: time.Now()}))
 			}
 		}()
 
+		// w.out is closed once w.rec is closed
+		closed := false
 		for {
 			select {
+			case now := <-tick.C:
+				// Report dropped records even if nothing else is logged
+				if closed {
+					continue
+				}
+				if warn := w.warnRecord(now); warn != nil {
+					if w.logBuffer == nil {
+						w.logBuffer = g_traceBufferPool.Get().(*TraceLogBuffer)
+						w.logBuffer.init()
+					}
+					w.encodeTo(w.logBuffer, warn)
+					w.out <- w.logBuffer
+					w.logBuffer = nil
+				}
 			case rec, ok := <-w.rec:
 				if !ok {
-					w.rec = make(chan *LogRecord, LogBufferLength)
+					closed = true
+					w.rec = make(chan *LogRecord, size)
 					continue
 				}
 				now := time.Now()
@@ -133,7 +170,10 @@ func NewFileLogTraceWriter(fname string, rotate bool) *FileLogTraceWriter {
 					w.logBuffer.init()
 				}
 
-				w.logBuffer.Encode(w.pieces, rec)
+				if warn := w.warnRecord(now); warn != nil {
+					w.encodeTo(w.logBuffer, warn)
+				}
+				w.encodeTo(w.logBuffer, rec)
 				if len(w.rec) == 0 || w.logBuffer.Flush() {
 					w.out <- w.logBuffer
 					w.logBuffer = nil
@@ -318,6 +358,7 @@ func normalSwitch(char byte, lb *TraceLogBuffer, cache *formatCacheType, rec *Lo
 		} else {
 			lb.buf.WriteString(`"info":"",`)
 		}
+		appendFieldsJSON(lb.buf, rec.Fields)
 		// no more fields
 		lb.buf.WriteString(`"cost":`)
 		lb.buf.WriteString(costValue)
@@ -388,6 +429,7 @@ func traceSwitch(char byte, lb *TraceLogBuffer, cache *formatCacheType, rec *Log
 		} else {
 			lb.buf.WriteString(`"x_msg":"",`)
 		}
+		appendFieldsJSON(lb.buf, rec.Fields)
 		// no more fields
 		lb.buf.WriteString(`"x_duration":` + costValue)
 
@@ -396,6 +438,14 @@ func traceSwitch(char byte, lb *TraceLogBuffer, cache *formatCacheType, rec *Log
 	}
 }
 
+func (w *FileLogTraceWriter) encodeTo(lb *TraceLogBuffer, rec *LogRecord) {
+	if w.format == FORMAT_JSON {
+		EncodeJSONRecord(lb.buf, rec)
+	} else {
+		lb.Encode(w.pieces, rec)
+	}
+}
+
 // Request that the logs rotate
 func (w *FileLogTraceWriter) Rotate() {
 	w.rot <- true
@@ -472,13 +522,22 @@ func (w *FileLogTraceWriter) intRotate() error {
 }
 
 // Set the logging format (chainable).  Must be called before the first log
-// message is written.
+// message is written.  FORMAT_JSON writes one json object per line.
 func (w *FileLogTraceWriter) SetFormat(format string) *FileLogTraceWriter {
 	w.format = format
 	w.pieces = bytes.Split([]byte(format), []byte{'%'})
 	return w
 }
 
+// SetOverflow sets what LogWrite does when the buffer is full (chainable).
+// Must be called before the first log message is written.  OverflowSpill
+// writes to fname.spill.
+func (w *FileLogTraceWriter) SetOverflow(policy OverflowPolicy) *FileLogTraceWriter {
+	w.policy = policy
+	w.spillName = w.filename + ".spill"
+	return w
+}
+
 // Set the logfile header and footer (chainable).  Must be called before the first log
 // message is written.  These are formatted similar to the FormatLogRecord (e.g.
 // you can use %D and %T in your header/footer for date and time).
@@ -0,0 +1,120 @@
+package log4go
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FORMAT_JSON 作为writer的format时每条日志输出为一行json对象
+// 字段依次为timestamp, level, source, host, tag, message, trace元数据, 结构化字段和cost
+// 结构化字段与前面的key重复时加field_前缀
+const FORMAT_JSON = "json"
+
+const jsonTimeLayout = "2006-01-02T15:04:05.000Z07:00"
+
+// FormatJSONRecord 将日志编码为一行json, 以换行结尾
+func FormatJSONRecord(rec *LogRecord) string {
+	if rec == nil {
+		return "<nil>"
+	}
+	buf := bytes.NewBuffer(make([]byte, 0, 256))
+	EncodeJSONRecord(buf, rec)
+	return buf.String()
+}
+
+// EncodeJSONRecord 将日志编码为一行json写入buf, 所有字符串按json规则转义, 不修改rec
+func EncodeJSONRecord(buf *bytes.Buffer, rec *LogRecord) {
+	tag, message, cost := splitMessage(rec.Message, rec.Fields)
+	source, host := rec.Source, ""
+	if i := strings.IndexByte(source, '\t'); i >= 0 {
+		source, host = source[:i], source[i+1:]
+	}
+
+	buf.WriteString(`{"timestamp":`)
+	appendJSONString(buf, rec.Created.Format(jsonTimeLayout))
+	buf.WriteString(`,"level":`)
+	appendJSONString(buf, g_levelMapper[rec.Level])
+	buf.WriteString(`,"source":`)
+	appendJSONString(buf, source)
+	if host != "" {
+		buf.WriteString(`,"host":`)
+		appendJSONString(buf, host)
+	}
+	buf.WriteString(`,"tag":`)
+	appendJSONString(buf, tag)
+	buf.WriteString(`,"message":`)
+	appendJSONString(buf, message)
+
+	// trace元数据按key排序, 便于比对
+	keys := make([]string, 0, len(rec.traceOptionals))
+	for key := range rec.traceOptionals {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	used := map[string]bool{"timestamp": true, "level": true, "source": true, "host": true, "tag": true, "message": true, "cost": true}
+	for _, key := range keys {
+		used[key] = true
+		buf.WriteByte(',')
+		appendJSONString(buf, key)
+		buf.WriteByte(':')
+		appendTraceValue(buf, rec.traceOptionals[key])
+	}
+
+	for _, f := range rec.Fields {
+		if f.Type == CostField {
+			continue
+		}
+		for used[f.Key] {
+			f.Key = "field_" + f.Key
+		}
+		used[f.Key] = true
+		buf.WriteByte(',')
+		f.AppendJSON(buf)
+	}
+	if cost != "" {
+		buf.WriteString(`,"cost":`)
+		appendJSONNumber(buf, cost)
+	}
+	buf.WriteString("}\n")
+}
+
+// splitMessage 拆分builder拼接的"tag\tmessage COST:x", 只有带CostField时才拆出cost, 消息中其余的"COST:"原样保留
+func splitMessage(msg string, fields []Field) (tag, message, cost string) {
+	message = msg
+	if i := strings.IndexByte(message, '\t'); i >= 0 {
+		tag, message = message[:i], message[i+1:]
+	}
+	for _, f := range fields {
+		if f.Type == CostField && strings.HasSuffix(message, " COST:"+f.Str) {
+			cost = f.Str
+			message = strings.TrimSuffix(message, " COST:"+f.Str)
+		}
+	}
+	return
+}
+
+// appendTraceValue trace元数据的值在写入时已带引号(如"\"abc\""), 去掉后重新转义, 其余按数字或字符串输出
+func appendTraceValue(buf *bytes.Buffer, value string) {
+	if n := len(value); n >= 2 && value[0] == '"' && value[n-1] == '"' {
+		if s, err := strconv.Unquote(value); err == nil {
+			appendJSONString(buf, s)
+		} else {
+			appendJSONString(buf, value[1:n-1])
+		}
+		return
+	}
+	appendJSONNumber(buf, value)
+}
+
+// appendJSONNumber 合法的json数字原样输出, 否则作为字符串
+func appendJSONNumber(buf *bytes.Buffer, value string) {
+	var n json.Number
+	if value != "" && json.Unmarshal([]byte(value), &n) == nil {
+		buf.WriteString(value)
+		return
+	}
+	appendJSONString(buf, value)
+}
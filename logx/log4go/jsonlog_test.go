@@ -0,0 +1,74 @@
+package log4go
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type captureWriter struct {
+	recs []*LogRecord
+}
+
+func (w *captureWriter) LogWrite(rec *LogRecord) {
+	w.recs = append(w.recs, rec)
+}
+
+func (w *captureWriter) Close() {}
+
+func TestJSONRecord(t *testing.T) {
+	w := &captureWriter{}
+	log := Logger{"test": &Filter{Level: DEBUG, LogWriter: w}}
+	log.LogFields(INFO, "a.go:10\t127.0.0.1", "[tag]\tsay \"hi\"\n COST:0.001500",
+		map[string]string{"x_trace_id": `"abc"`, "x_rpcid": `"0.1"`, "x_code": "200"},
+		[]Field{{Key: "uid", Type: StringField, Str: "u\t1"}, {Key: "age", Type: IntField, Integer: 18}, NewCostField("0.001500")})
+	if !assert.Len(t, w.recs, 1) {
+		return
+	}
+	line := FormatLogRecord(FORMAT_JSON, w.recs[0])
+	assert.True(t, strings.HasSuffix(line, "}\n"))
+
+	var out map[string]interface{}
+	if !assert.NoError(t, json.Unmarshal([]byte(line), &out)) {
+		return
+	}
+	assert.Equal(t, "info", out["level"])
+	assert.Equal(t, "a.go:10", out["source"])
+	assert.Equal(t, "127.0.0.1", out["host"])
+	assert.Equal(t, "[tag]", out["tag"])
+	assert.Equal(t, "say \"hi\"\n", out["message"])
+	assert.Equal(t, "abc", out["x_trace_id"])
+	assert.Equal(t, "0.1", out["x_rpcid"])
+	assert.Equal(t, float64(200), out["x_code"])
+	assert.Equal(t, "u\t1", out["uid"])
+	assert.Equal(t, float64(18), out["age"])
+	assert.Equal(t, 0.0015, out["cost"])
+	// 文本格式中耗时只出现在消息末尾
+	assert.Equal(t, "[tag]\tsay \"hi\"\n COST:0.001500 uid=\"u\\t1\" age=18\n", FormatLogRecord("%M", w.recs[0]))
+
+	// 没有CostField时消息中的COST:原样保留, 与固定key重复的字段加前缀
+	log.LogFields(INFO, "a.go:10", "[tag]\tunit COST: 12 yuan",
+		map[string]string{"x_trace_id": `"abc"`},
+		[]Field{{Key: "message", Type: StringField, Str: "m"}, {Key: "level", Type: IntField, Integer: 1},
+			{Key: "x_trace_id", Type: StringField, Str: "t"}, {Key: "uid", Type: StringField, Str: "a"}, {Key: "uid", Type: StringField, Str: "b"}})
+	if !assert.Len(t, w.recs, 2) {
+		return
+	}
+	line = FormatLogRecord(FORMAT_JSON, w.recs[1])
+	out = nil
+	if !assert.NoError(t, json.Unmarshal([]byte(line), &out)) {
+		return
+	}
+	assert.Equal(t, "unit COST: 12 yuan", out["message"])
+	assert.NotContains(t, out, "cost")
+	assert.Equal(t, "info", out["level"])
+	assert.Equal(t, "m", out["field_message"])
+	assert.Equal(t, float64(1), out["field_level"])
+	assert.Equal(t, "abc", out["x_trace_id"])
+	assert.Equal(t, "t", out["field_x_trace_id"])
+	assert.Equal(t, "a", out["uid"])
+	assert.Equal(t, "b", out["field_uid"])
+	assert.Equal(t, 1, strings.Count(line, `"message":`))
+}
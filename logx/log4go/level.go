@@ -0,0 +1,117 @@
+package log4go
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 运行时调整的filter级别, key为*Filter, 不修改Filter.Level以便恢复
+var (
+	filterLevels sync.Map
+	// filterOverrides 调整过的filter数量, 为0时跳过查找
+	filterOverrides int32
+)
+
+// level filter当前生效的级别
+func (f *Filter) level() Level {
+	if atomic.LoadInt32(&filterOverrides) == 0 {
+		return f.Level
+	}
+	if lvl, ok := filterLevels.Load(f); ok {
+		return lvl.(Level)
+	}
+	return f.Level
+}
+
+// SetFilterLevel changes the level of the named filter at runtime, safe for
+// concurrent use with logging.  Returns false if there is no such filter.
+func (log Logger) SetFilterLevel(name string, lvl Level) bool {
+	filt, ok := log[name]
+	if !ok {
+		return false
+	}
+	if _, loaded := filterLevels.LoadOrStore(filt, lvl); loaded {
+		filterLevels.Store(filt, lvl)
+	} else {
+		atomic.AddInt32(&filterOverrides, 1)
+	}
+	return true
+}
+
+// ResetFilterLevel restores the configured level of the named filter.
+func (log Logger) ResetFilterLevel(name string) {
+	filt, ok := log[name]
+	if !ok {
+		return
+	}
+	if _, loaded := filterLevels.LoadAndDelete(filt); loaded {
+		atomic.AddInt32(&filterOverrides, -1)
+	}
+}
+
+// FilterLevels returns the level currently in effect for each filter.
+func (log Logger) FilterLevels() map[string]Level {
+	levels := make(map[string]Level, len(log))
+	for name, filt := range log {
+		levels[name] = filt.level()
+	}
+	return levels
+}
+
+// Wrapper for (*Logger).SetFilterLevel
+func SetFilterLevel(name string, lvl Level) bool {
+	return Global.SetFilterLevel(name, lvl)
+}
+
+// Wrapper for (*Logger).ResetFilterLevel
+func ResetFilterLevel(name string) {
+	Global.ResetFilterLevel(name)
+}
+
+// Wrapper for (*Logger).FilterLevels
+func FilterLevels() map[string]Level {
+	return Global.FilterLevels()
+}
+
+// LogFloor sends a log record like LogFields, but filters whose level is at or
+// below gate accept the record as long as lvl >= floor.  It is used to raise
+// the verbosity of a single request without touching the filters; filters above
+// gate (e.g. an error-only file) keep their own level.
+func (log Logger) LogFloor(lvl, floor, gate Level, source, message string, traceFields map[string]string, fields []Field) {
+	accept := func(filt *Filter) bool {
+		filtLevel := filt.level()
+		return lvl >= filtLevel || (filtLevel <= gate && lvl >= floor)
+	}
+
+	skip := true
+	for _, filt := range log {
+		if accept(filt) {
+			skip = false
+			break
+		}
+	}
+	if skip {
+		return
+	}
+
+	rec := &LogRecord{
+		Level:          lvl,
+		Created:        time.Now(),
+		Source:         source,
+		Message:        message,
+		Fields:         fields,
+		useTrace:       traceFields != nil,
+		traceOptionals: traceFields,
+	}
+	for _, filt := range log {
+		if accept(filt) {
+			filt.LogWrite(rec)
+		}
+	}
+}
+
+// Wrapper for (*Logger).LogFloor
+func LogFloor(lvl, floor, gate Level, source, message string, traceFields map[string]string, fields []Field) {
+	Global.LogFloor(lvl, floor, gate, source, message, traceFields, fields)
+}
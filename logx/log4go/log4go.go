@@ -105,6 +105,7 @@ type LogRecord struct {
 	Created time.Time // The time at which the log message was created (nanoseconds)
 	Source  string    // The message source
 	Message string    // The log message
+	Fields  []Field   // Structured fields, encoded by each writer
 
 	//    Added by xueersi
 	// used for identify `x_timestamp` field which uses cache time by default
@@ -184,6 +185,7 @@ func (log Logger) Close() {
 	// Close all open loggers
 	for name, filt := range log {
 		filt.Close()
+		log.ResetFilterLevel(name)
 		delete(log, name)
 	}
 }
@@ -196,6 +198,18 @@ func (log Logger) AddFilter(name string, lvl Level, writer LogWriter) Logger {
 	return log
 }
 
+// Dropped returns how many records each filter has dropped because its buffer
+// was full.  Only writers with an overflow policy are reported.
+func (log Logger) Dropped() map[string]uint64 {
+	dropped := make(map[string]uint64)
+	for name, filt := range log {
+		if d, ok := filt.LogWriter.(interface{ Dropped() uint64 }); ok {
+			dropped[name] = d.Dropped()
+		}
+	}
+	return dropped
+}
+
 /******* Logging *******/
 // Send a formatted log message internally
 func (log Logger) intLogf(lvl Level, format string, args ...interface{}) {
@@ -203,7 +217,7 @@ func (log Logger) intLogf(lvl Level, format string, args ...interface{}) {
 
 	// Determine if any logging will be done
 	for _, filt := range log {
-		if lvl >= filt.Level {
+		if lvl >= filt.level() {
 			skip = false
 			break
 		}
@@ -234,7 +248,7 @@ func (log Logger) intLogf(lvl Level, format string, args ...interface{}) {
 
 	// Dispatch the logs
 	for _, filt := range log {
-		if lvl < filt.Level {
+		if lvl < filt.level() {
 			continue
 		}
 		filt.LogWrite(rec)
@@ -247,7 +261,7 @@ func (log Logger) intLogc(lvl Level, closure func() string) {
 
 	// Determine if any logging will be done
 	for _, filt := range log {
-		if lvl >= filt.Level {
+		if lvl >= filt.level() {
 			skip = false
 			break
 		}
@@ -273,7 +287,7 @@ func (log Logger) intLogc(lvl Level, closure func() string) {
 
 	// Dispatch the logs
 	for _, filt := range log {
-		if lvl < filt.Level {
+		if lvl < filt.level() {
 			continue
 		}
 		filt.LogWrite(rec)
@@ -286,7 +300,7 @@ func (log Logger) Log(lvl Level, source, message string) {
 
 	// Determine if any logging will be done
 	for _, filt := range log {
-		if lvl >= filt.Level {
+		if lvl >= filt.level() {
 			skip = false
 			break
 		}
@@ -305,7 +319,7 @@ func (log Logger) Log(lvl Level, source, message string) {
 
 	// Dispatch the logs
 	for _, filt := range log {
-		if lvl < filt.Level {
+		if lvl < filt.level() {
 			continue
 		}
 		filt.LogWrite(rec)
@@ -321,7 +335,7 @@ func (log Logger) LogTrace(lvl Level, source, message string, traceMeta *map[str
 
 	// Determine if any logging will be done
 	for _, filt := range log {
-		if lvl >= filt.Level {
+		if lvl >= filt.level() {
 			skip = false
 			break
 		}
@@ -348,7 +362,7 @@ func (log Logger) LogTrace(lvl Level, source, message string, traceMeta *map[str
 
 	// Dispatch the logs
 	for _, filt := range log {
-		if lvl < filt.Level {
+		if lvl < filt.level() {
 			continue
 		}
 		filt.LogWrite(rec)
@@ -360,7 +374,7 @@ func (log Logger) LogTraceMap(lvl Level, source, message string, traceFields map
 
 	// Determine if any logging will be done
 	for _, filt := range log {
-		if lvl >= filt.Level {
+		if lvl >= filt.level() {
 			skip = false
 			break
 		}
@@ -381,7 +395,42 @@ func (log Logger) LogTraceMap(lvl Level, source, message string, traceFields map
 
 	// Dispatch the logs
 	for _, filt := range log {
-		if lvl < filt.Level {
+		if lvl < filt.level() {
+			continue
+		}
+		filt.LogWrite(rec)
+	}
+}
+
+// LogFields 带结构化字段的日志, traceFields不为空时按trace格式输出
+func (log Logger) LogFields(lvl Level, source, message string, traceFields map[string]string, fields []Field) {
+	skip := true
+
+	// Determine if any logging will be done
+	for _, filt := range log {
+		if lvl >= filt.level() {
+			skip = false
+			break
+		}
+	}
+	if skip {
+		return
+	}
+
+	// Make the log record
+	rec := &LogRecord{
+		Level:          lvl,
+		Created:        time.Now(),
+		Source:         source,
+		Message:        message,
+		Fields:         fields,
+		useTrace:       traceFields != nil,
+		traceOptionals: traceFields,
+	}
+
+	// Dispatch the logs
+	for _, filt := range log {
+		if lvl < filt.level() {
 			continue
 		}
 		filt.LogWrite(rec)
@@ -0,0 +1,161 @@
+package log4go
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// OverflowPolicy 文件writer的缓冲已满时的处理策略
+type OverflowPolicy int
+
+const (
+	// OverflowBlock 阻塞等待, 不丢日志, 磁盘慢时会拖慢业务
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropNewest 丢弃当前日志
+	OverflowDropNewest
+	// OverflowDropOldest 丢弃缓冲中最早的一条日志, 保留当前日志
+	OverflowDropOldest
+	// OverflowSpill 同步写入旁路文件(日志文件名.spill), 不丢日志, 需要单独采集
+	OverflowSpill
+)
+
+var overflowNames = map[OverflowPolicy]string{
+	OverflowBlock:      "block",
+	OverflowDropNewest: "drop-newest",
+	OverflowDropOldest: "drop-oldest",
+	OverflowSpill:      "spill",
+}
+
+// DropWarnInterval 两次"N records dropped"告警之间的最小间隔
+var DropWarnInterval = 10 * time.Second
+
+func (p OverflowPolicy) String() string {
+	if name, ok := overflowNames[p]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// ParseOverflowPolicy 解析block、drop-newest、drop-oldest、spill
+func ParseOverflowPolicy(name string) (OverflowPolicy, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for p, n := range overflowNames {
+		if n == name {
+			return p, true
+		}
+	}
+	return OverflowBlock, false
+}
+
+// overflow 文件writer共用的写入缓冲溢出处理, 记录丢弃数量
+type overflow struct {
+	policy OverflowPolicy
+	// dropped 累计丢弃数, unwarned 尚未告警的丢弃数
+	dropped  uint64
+	unwarned uint64
+	// lastWarn 只在writer的消费协程中读写
+	lastWarn time.Time
+
+	spillName string
+	spillLock sync.Mutex
+	spillFile *os.File
+	// encode 旁路文件中的日志格式与主文件一致
+	encode func(rec *LogRecord) []byte
+}
+
+// Dropped 累计丢弃的日志条数
+func (o *overflow) Dropped() uint64 {
+	return atomic.LoadUint64(&o.dropped)
+}
+
+func (o *overflow) drop() {
+	atomic.AddUint64(&o.dropped, 1)
+	atomic.AddUint64(&o.unwarned, 1)
+}
+
+// send 按策略写入缓冲
+func (o *overflow) send(ch chan *LogRecord, rec *LogRecord) {
+	if o.policy == OverflowBlock {
+		ch <- rec
+		return
+	}
+	select {
+	case ch <- rec:
+		return
+	default:
+	}
+
+	switch o.policy {
+	case OverflowDropOldest:
+		// 与消费协程及其他写入方竞争, 重试几次后丢弃当前日志
+		for i := 0; i < 3; i++ {
+			select {
+			case <-ch:
+				o.drop()
+			default:
+			}
+			select {
+			case ch <- rec:
+				return
+			default:
+			}
+		}
+	case OverflowSpill:
+		if o.spill(rec) {
+			return
+		}
+	}
+	o.drop()
+}
+
+func (o *overflow) spill(rec *LogRecord) bool {
+	if o.encode == nil || o.spillName == "" {
+		return false
+	}
+	o.spillLock.Lock()
+	defer o.spillLock.Unlock()
+	if o.spillFile == nil {
+		fd, err := os.OpenFile(o.spillName, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0664)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "log4go spill(%q): %s\n", o.spillName, err)
+			return false
+		}
+		o.spillFile = fd
+	}
+	if _, err := o.spillFile.Write(o.encode(rec)); err != nil {
+		fmt.Fprintf(os.Stderr, "log4go spill(%q): %s\n", o.spillName, err)
+		return false
+	}
+	return true
+}
+
+func (o *overflow) closeSpill() {
+	o.spillLock.Lock()
+	defer o.spillLock.Unlock()
+	if o.spillFile != nil {
+		o.spillFile.Close()
+		o.spillFile = nil
+	}
+}
+
+// warnRecord 有未告警的丢弃且距上次告警超过DropWarnInterval时, 返回写入日志文件的告警
+func (o *overflow) warnRecord(now time.Time) *LogRecord {
+	if atomic.LoadUint64(&o.unwarned) == 0 || now.Sub(o.lastWarn) < DropWarnInterval {
+		return nil
+	}
+	n := atomic.SwapUint64(&o.unwarned, 0)
+	if n == 0 {
+		return nil
+	}
+	o.lastWarn = now
+	return &LogRecord{
+		Level:   WARNING,
+		Created: now,
+		Source:  "log4go",
+		Message: fmt.Sprintf("[log4go_overflow]\t%d records dropped, policy:%s, total:%d", n, o.policy, o.Dropped()),
+	}
+}
@@ -0,0 +1,178 @@
+package log4go
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseOverflowPolicy(t *testing.T) {
+	for _, name := range []string{"block", "drop-newest", "drop-oldest", "spill"} {
+		p, ok := ParseOverflowPolicy(" " + strings.ToUpper(name))
+		assert.True(t, ok)
+		assert.Equal(t, name, p.String())
+	}
+	p, ok := ParseOverflowPolicy("drop")
+	assert.False(t, ok)
+	assert.Equal(t, OverflowBlock, p)
+}
+
+// TestOverflowSend 缓冲为1且无人消费, 第二条日志起按策略处理
+func TestOverflowSend(t *testing.T) {
+	dir, err := ioutil.TempDir("", "log4go")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	rec := func(msg string) *LogRecord {
+		return &LogRecord{Level: INFO, Created: time.Now(), Message: msg}
+	}
+	encode := func(r *LogRecord) []byte {
+		return []byte(FormatLogRecord("%M", r))
+	}
+
+	// block: 缓冲有空位后写入, 不丢弃
+	o := &overflow{policy: OverflowBlock}
+	ch := make(chan *LogRecord, 1)
+	o.send(ch, rec("a"))
+	sent := make(chan struct{})
+	go func() {
+		o.send(ch, rec("b"))
+		close(sent)
+	}()
+	select {
+	case <-sent:
+		t.Fatal("block policy should wait for the buffer")
+	case <-time.After(50 * time.Millisecond):
+	}
+	assert.Equal(t, "a", (<-ch).Message)
+	<-sent
+	assert.Equal(t, "b", (<-ch).Message)
+	assert.Equal(t, uint64(0), o.Dropped())
+
+	// drop-newest: 保留缓冲中的日志
+	o = &overflow{policy: OverflowDropNewest}
+	ch = make(chan *LogRecord, 1)
+	o.send(ch, rec("a"))
+	o.send(ch, rec("b"))
+	o.send(ch, rec("c"))
+	assert.Equal(t, uint64(2), o.Dropped())
+	assert.Equal(t, "a", (<-ch).Message)
+
+	// drop-oldest: 保留最新的日志
+	o = &overflow{policy: OverflowDropOldest}
+	ch = make(chan *LogRecord, 1)
+	o.send(ch, rec("a"))
+	o.send(ch, rec("b"))
+	o.send(ch, rec("c"))
+	assert.Equal(t, uint64(2), o.Dropped())
+	assert.Equal(t, "c", (<-ch).Message)
+
+	// spill: 溢出的日志写入旁路文件
+	spillName := filepath.Join(dir, "a.log.spill")
+	o = &overflow{policy: OverflowSpill, spillName: spillName, encode: encode}
+	ch = make(chan *LogRecord, 1)
+	o.send(ch, rec("a"))
+	o.send(ch, rec("b"))
+	o.send(ch, rec("c"))
+	o.closeSpill()
+	assert.Equal(t, uint64(0), o.Dropped())
+	assert.Equal(t, "a", (<-ch).Message)
+	data, err := ioutil.ReadFile(spillName)
+	assert.NoError(t, err)
+	assert.Equal(t, "b\nc\n", string(data))
+
+	// 旁路文件无法打开时丢弃
+	o = &overflow{policy: OverflowSpill, spillName: filepath.Join(dir, "missing", "a.log.spill"), encode: encode}
+	ch = make(chan *LogRecord, 1)
+	o.send(ch, rec("a"))
+	o.send(ch, rec("b"))
+	o.closeSpill()
+	assert.Equal(t, uint64(1), o.Dropped())
+}
+
+func TestOverflowWarnRecord(t *testing.T) {
+	o := &overflow{policy: OverflowDropNewest}
+	now := time.Now()
+	assert.Nil(t, o.warnRecord(now))
+
+	o.drop()
+	o.drop()
+	warn := o.warnRecord(now)
+	if assert.NotNil(t, warn) {
+		assert.Equal(t, WARNING, warn.Level)
+		assert.Equal(t, "[log4go_overflow]\t2 records dropped, policy:drop-newest, total:2", warn.Message)
+	}
+	// 已告警的丢弃不再重复告警
+	assert.Nil(t, o.warnRecord(now.Add(DropWarnInterval)))
+
+	// 间隔内的丢弃推迟到下一个间隔告警, 数量累计
+	o.drop()
+	assert.Nil(t, o.warnRecord(now.Add(time.Second)))
+	o.drop()
+	warn = o.warnRecord(now.Add(DropWarnInterval))
+	if assert.NotNil(t, warn) {
+		assert.Equal(t, "[log4go_overflow]\t2 records dropped, policy:drop-newest, total:4", warn.Message)
+	}
+	assert.Equal(t, uint64(4), o.Dropped())
+}
+
+// TestFileLogWriterOverflow 缓冲为1时大量写入, 写入文件、旁路文件和丢弃的条数之和等于写入条数
+func TestFileLogWriterOverflow(t *testing.T) {
+	dir, err := ioutil.TempDir("", "log4go")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	const total = 500
+	for _, policy := range []OverflowPolicy{OverflowBlock, OverflowDropNewest, OverflowDropOldest, OverflowSpill} {
+		name := filepath.Join(dir, policy.String()+".log")
+		w := NewFileLogWriterWithBuffer(name, false, 1).SetFormat("%M").SetOverflow(policy)
+		if !assert.NotNil(t, w) {
+			return
+		}
+		log := Logger{"file": &Filter{Level: DEBUG, LogWriter: w}}
+		for i := 0; i < total; i++ {
+			log.Log(INFO, "test", fmt.Sprintf("line %d", i))
+		}
+		dropped := w.Dropped()
+		assert.Equal(t, map[string]uint64{"file": dropped}, log.Dropped())
+		if policy == OverflowBlock || policy == OverflowSpill {
+			assert.Equal(t, uint64(0), dropped, policy.String())
+		}
+		log.Close()
+
+		// Close后消费协程写完缓冲中的日志再关闭文件
+		assert.Eventually(t, func() bool {
+			return countLines(name, "line ")+countLines(name+".spill", "line ")+int(dropped) == total
+		}, time.Second, 10*time.Millisecond, policy.String())
+		if dropped > 0 {
+			assert.Equal(t, 0, countLines(name+".spill", "line "), policy.String())
+		}
+	}
+}
+
+// countLines 文件中以prefix开头的行数, 文件不存在时为0
+func countLines(name, prefix string) int {
+	f, err := os.Open(name)
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+	n := 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if strings.HasPrefix(scanner.Text(), prefix) {
+			n++
+		}
+	}
+	return n
+}
@@ -48,6 +48,7 @@ func getFormatCache() *formatCacheType {
 // %M - Message
 // Ignores unknown formats
 // Recommended: "[%D %T] [%L] (%S) %M"
+// FORMAT_JSON outputs one json object per line, see EncodeJSONRecord
 func FormatLogRecord(format string, rec *LogRecord) string {
 	if rec == nil {
 		return "<nil>"
@@ -55,6 +56,9 @@ func FormatLogRecord(format string, rec *LogRecord) string {
 	if len(format) == 0 {
 		return ""
 	}
+	if format == FORMAT_JSON {
+		return FormatJSONRecord(rec)
+	}
 
 	out := bytes.NewBuffer(make([]byte, 0, 64))
 	secs := rec.Created.UnixNano() / 1e9
@@ -101,6 +105,7 @@ func FormatLogRecord(format string, rec *LogRecord) string {
 				out.WriteString(slice[len(slice)-1])
 			case 'M':
 				out.WriteString(rec.Message)
+				appendFieldsText(out, rec.Fields)
 			}
 			if len(piece) > 1 {
 				out.Write(piece[1:])
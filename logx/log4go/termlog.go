@@ -26,6 +26,8 @@ func NewConsoleLogWriter() *ConsoleLogWriter {
 	go consoleWriter.run(stdout)
 	return consoleWriter
 }
+
+// SetFormat FORMAT_JSON outputs one json object per line
 func (c *ConsoleLogWriter) SetFormat(format string) {
 	c.format = format
 }
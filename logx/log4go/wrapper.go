@@ -32,6 +32,11 @@ func AddFilter(name string, lvl Level, writer LogWriter) {
 	Global.AddFilter(name, lvl, writer)
 }
 
+// Wrapper for (*Logger).Dropped
+func Dropped() map[string]uint64 {
+	return Global.Dropped()
+}
+
 // Wrapper for (*Logger).Close (closes and removes all logwriters)
 func Close() {
 	Global.Close()
@@ -108,6 +113,10 @@ func LogTraceMap(lvl Level, source, message string, traceFields map[string]strin
 	Global.LogTraceMap(lvl, source, message, traceFields)
 }
 
+func LogFields(lvl Level, source, message string, traceFields map[string]string, fields []Field) {
+	Global.LogFields(lvl, source, message, traceFields, fields)
+}
+
 // Send a formatted log message easily
 // Wrapper for (*Logger).Logf
 func Logf(lvl Level, format string, args ...interface{}) {
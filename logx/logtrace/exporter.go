@@ -0,0 +1,374 @@
+package logtrace
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// SpanExporter span导出器, Export由后台协程按批调用
+type SpanExporter interface {
+	Export(spans []*Span) error
+	Shutdown() error
+}
+
+// ExporterConfig 批量导出配置
+type ExporterConfig struct {
+	// BatchSize 单批最大span数, 默认512
+	BatchSize int
+	// QueueSize 待导出队列长度, 队列满时丢弃新span, 默认2048
+	QueueSize int
+	// Interval 最长导出间隔, 默认5s
+	Interval time.Duration
+}
+
+type spanProcessor struct {
+	exporter SpanExporter
+	config   ExporterConfig
+	queue    chan *Span
+	flush    chan chan struct{}
+	done     chan struct{}
+	stopped  chan struct{}
+}
+
+var (
+	processor     *spanProcessor
+	processorLock sync.RWMutex
+)
+
+// InitSpanExporter 启动span导出, 重复调用会先关闭之前的导出器
+func InitSpanExporter(exporter SpanExporter, config ExporterConfig) {
+	if config.BatchSize <= 0 {
+		config.BatchSize = 512
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = 2048
+	}
+	if config.Interval <= 0 {
+		config.Interval = 5 * time.Second
+	}
+	p := &spanProcessor{
+		exporter: exporter,
+		config:   config,
+		queue:    make(chan *Span, config.QueueSize),
+		flush:    make(chan chan struct{}),
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+	go p.run()
+
+	processorLock.Lock()
+	old := processor
+	processor = p
+	processorLock.Unlock()
+	if old != nil {
+		old.shutdown()
+	}
+}
+
+// FlushSpans 立即导出队列中的span
+func FlushSpans() {
+	processorLock.RLock()
+	p := processor
+	processorLock.RUnlock()
+	if p != nil {
+		p.flushSync()
+	}
+}
+
+// CloseSpanExporter 导出剩余span并关闭导出器
+func CloseSpanExporter() {
+	processorLock.Lock()
+	p := processor
+	processor = nil
+	processorLock.Unlock()
+	if p != nil {
+		p.shutdown()
+	}
+}
+
+func exportSpan(s *Span) {
+	processorLock.RLock()
+	defer processorLock.RUnlock()
+	if processor == nil {
+		return
+	}
+	select {
+	case processor.queue <- s:
+	default:
+	}
+}
+
+func (p *spanProcessor) run() {
+	defer close(p.stopped)
+	ticker := time.NewTicker(p.config.Interval)
+	defer ticker.Stop()
+	batch := make([]*Span, 0, p.config.BatchSize)
+	export := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := p.exporter.Export(batch); err != nil {
+			fmt.Fprintf(os.Stderr, "logtrace: export %d spans failed: %v\n", len(batch), err)
+		}
+		batch = make([]*Span, 0, p.config.BatchSize)
+	}
+	drain := func() {
+		for {
+			select {
+			case s := <-p.queue:
+				batch = append(batch, s)
+				if len(batch) >= p.config.BatchSize {
+					export()
+				}
+			default:
+				export()
+				return
+			}
+		}
+	}
+	for {
+		select {
+		case s := <-p.queue:
+			batch = append(batch, s)
+			if len(batch) >= p.config.BatchSize {
+				export()
+			}
+		case <-ticker.C:
+			export()
+		case ch := <-p.flush:
+			drain()
+			close(ch)
+		case <-p.done:
+			drain()
+			return
+		}
+	}
+}
+
+func (p *spanProcessor) flushSync() {
+	ch := make(chan struct{})
+	select {
+	case p.flush <- ch:
+		<-ch
+	case <-p.stopped:
+	}
+}
+
+func (p *spanProcessor) shutdown() {
+	close(p.done)
+	<-p.stopped
+	p.exporter.Shutdown()
+}
+
+/****** OTLP/JSON ******/
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceId           string         `json:"traceId"`
+	SpanId            string         `json:"spanId"`
+	ParentSpanId      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              SpanKind       `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpStatus struct {
+	Code    StatusCode `json:"code"`
+	Message string     `json:"message,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+}
+
+// EncodeOTLP 按OTLP/JSON(ExportTraceServiceRequest)编码
+func EncodeOTLP(serviceName string, spans []*Span) ([]byte, error) {
+	out := make([]otlpSpan, 0, len(spans))
+	for _, s := range spans {
+		s.lock.Lock()
+		out = append(out, otlpSpan{
+			TraceId:           s.TraceId,
+			SpanId:            s.SpanId,
+			ParentSpanId:      s.ParentSpanId,
+			Name:              s.Name,
+			Kind:              s.Kind,
+			StartTimeUnixNano: strconv.FormatInt(s.StartTime.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.EndTime.UnixNano(), 10),
+			Attributes:        otlpAttributes(s.Attributes),
+			Status:            otlpStatus{Code: s.StatusCode, Message: s.StatusMessage},
+		})
+		s.lock.Unlock()
+	}
+	req := otlpRequest{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource: otlpResource{
+				Attributes: otlpAttributes(map[string]interface{}{"service.name": serviceName}),
+			},
+			ScopeSpans: []otlpScopeSpans{{
+				Scope: otlpScope{Name: "github.com/ytf606/golibs/logx/logtrace"},
+				Spans: out,
+			}},
+		}},
+	}
+	return json.Marshal(req)
+}
+
+func otlpAttributes(attrs map[string]interface{}) []otlpKeyValue {
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	kvs := make([]otlpKeyValue, 0, len(keys))
+	for _, k := range keys {
+		kvs = append(kvs, otlpKeyValue{Key: k, Value: toOtlpValue(attrs[k])})
+	}
+	return kvs
+}
+
+func toOtlpValue(v interface{}) otlpValue {
+	switch t := v.(type) {
+	case bool:
+		return otlpValue{BoolValue: &t}
+	case int:
+		s := strconv.FormatInt(int64(t), 10)
+		return otlpValue{IntValue: &s}
+	case int32:
+		s := strconv.FormatInt(int64(t), 10)
+		return otlpValue{IntValue: &s}
+	case int64:
+		s := strconv.FormatInt(t, 10)
+		return otlpValue{IntValue: &s}
+	case float32:
+		f := float64(t)
+		return otlpValue{DoubleValue: &f}
+	case float64:
+		return otlpValue{DoubleValue: &t}
+	case string:
+		return otlpValue{StringValue: &t}
+	default:
+		s := fmt.Sprint(t)
+		return otlpValue{StringValue: &s}
+	}
+}
+
+/****** File exporter ******/
+
+type fileExporter struct {
+	serviceName string
+	file        *os.File
+	lock        sync.Mutex
+}
+
+// NewFileExporter 每批写入一行OTLP/JSON, 可由collector的otlpjsonfile receiver读取
+func NewFileExporter(path, serviceName string) (SpanExporter, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &fileExporter{serviceName: serviceName, file: f}, nil
+}
+
+func (e *fileExporter) Export(spans []*Span) error {
+	data, err := EncodeOTLP(e.serviceName, spans)
+	if err != nil {
+		return err
+	}
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	_, err = e.file.Write(append(data, '\n'))
+	return err
+}
+
+func (e *fileExporter) Shutdown() error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	return e.file.Close()
+}
+
+/****** HTTP exporter ******/
+
+type httpExporter struct {
+	serviceName string
+	endpoint    string
+	headers     map[string]string
+	client      *http.Client
+}
+
+// NewHTTPExporter 以OTLP/HTTP JSON发送到collector, endpoint如http://127.0.0.1:4318/v1/traces
+func NewHTTPExporter(endpoint, serviceName string, headers map[string]string) SpanExporter {
+	return &httpExporter{
+		serviceName: serviceName,
+		endpoint:    endpoint,
+		headers:     headers,
+		client:      &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (e *httpExporter) Export(spans []*Span) error {
+	data, err := EncodeOTLP(e.serviceName, spans)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, e.endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("otlp http export status:%d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (e *httpExporter) Shutdown() error {
+	e.client.CloseIdleConnections()
+	return nil
+}
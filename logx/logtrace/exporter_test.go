@@ -0,0 +1,59 @@
+package logtrace
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ytf606/golibs/errorx"
+)
+
+func TestHTTPExporter(t *testing.T) {
+	var (
+		lock     sync.Mutex
+		received []otlpRequest
+	)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		var req otlpRequest
+		assert.Nil(t, json.Unmarshal(body, &req))
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		lock.Lock()
+		received = append(received, req)
+		lock.Unlock()
+	}))
+	defer collector.Close()
+
+	InitSpanExporter(NewHTTPExporter(collector.URL+"/v1/traces", "golibs-test", nil), ExporterConfig{})
+	defer CloseSpanExporter()
+
+	node := GenLogTraceMetadata()
+	ctx := context.WithValue(context.Background(), GetMetadataKey(), node)
+	ctx, parent := StartSpan(ctx, "GET /orders", SpanKindServer)
+	_, child := StartSpan(ctx, "redis.get", SpanKindClient)
+	child.SetError(errorx.New500Response(errorx.RedisConnectErr, "redis down"))
+	child.End()
+	parent.SetAttribute("http.status_code", 200).End()
+	FlushSpans()
+
+	lock.Lock()
+	defer lock.Unlock()
+	if !assert.Len(t, received, 1) {
+		return
+	}
+	rs := received[0].ResourceSpans[0]
+	assert.Equal(t, "golibs-test", *rs.Resource.Attributes[0].Value.StringValue)
+	spans := rs.ScopeSpans[0].Spans
+	assert.Len(t, spans, 2)
+	assert.Equal(t, "redis.get", spans[0].Name)
+	assert.Equal(t, spans[1].SpanId, spans[0].ParentSpanId)
+	assert.Equal(t, spans[1].TraceId, spans[0].TraceId)
+	assert.Equal(t, W3CTraceId(node.Get("x_trace_id")), spans[0].TraceId)
+	assert.Equal(t, StatusError, spans[0].Status.Code)
+	assert.Equal(t, SpanKindServer, spans[1].Kind)
+}
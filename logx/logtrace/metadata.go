@@ -21,9 +21,13 @@ func AppendLogTraceMetadataContext(ctx context.Context, metadata map[string]stri
 		return ctx
 	}
 	t := NewTraceNode()
+	ExtractHeaders(t, func(key string) string {
+		return metadata[key]
+	})
 	for k, v := range metadata {
-		if _, ok := enableMetadataKey[k]; ok {
-			t.Set(k, v)
+		if _, ok := enableMetadataKey[k]; ok && v != "" {
+			// rpcx透传的metadata不带引号, 与本地TraceNode格式保持一致
+			t.Set(k, quote(unquote(v)))
 		}
 	}
 	ctx = context.WithValue(ctx, GetMetadataKey(), t)
@@ -0,0 +1,178 @@
+package logtrace
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+)
+
+// W3C Trace Context与B3透传相关header
+const (
+	HeaderTraceparent    = "traceparent"
+	HeaderTracestate     = "tracestate"
+	HeaderB3             = "b3"
+	HeaderB3TraceId      = "X-B3-TraceId"
+	HeaderB3SpanId       = "X-B3-SpanId"
+	HeaderB3ParentSpanId = "X-B3-ParentSpanId"
+	HeaderB3Sampled      = "X-B3-Sampled"
+	HeaderTraceId        = "traceid"
+	HeaderRpcId          = "rpcid"
+)
+
+// ExtractHeaders 解析traceparent/tracestate或B3头, 映射到x_trace_id、x_parent_span_id、x_sampled
+// traceparent优先于B3, 未解析到任何trace信息时返回false
+func ExtractHeaders(t *TraceNode, get func(key string) string) bool {
+	if traceId, spanId, sampled, ok := parseTraceparent(get(HeaderTraceparent)); ok {
+		setTrace(t, traceId, spanId, sampled)
+		if state := get(HeaderTracestate); state != "" {
+			t.Set("x_tracestate", quote(state))
+		}
+		return true
+	}
+	if traceId, spanId, sampled, ok := parseB3Single(get(HeaderB3)); ok {
+		setTrace(t, traceId, spanId, sampled)
+		return true
+	}
+	if traceId := strings.ToLower(get(HeaderB3TraceId)); isHex(traceId, 16) || isHex(traceId, 32) {
+		setTrace(t, traceId, strings.ToLower(get(HeaderB3SpanId)), get(HeaderB3Sampled) != "0")
+		return true
+	}
+	return false
+}
+
+// InjectHeaders 生成调用下游时透传的header, 同时输出W3C、B3和原有traceid/rpcid
+func InjectHeaders(ctx context.Context) map[string]string {
+	if ctx == nil {
+		return map[string]string{}
+	}
+	meta := ctx.Value(GetMetadataKey())
+	t, ok := meta.(*TraceNode)
+	if !ok || t == nil {
+		return map[string]string{}
+	}
+	xTraceId := unquote(t.Get("x_trace_id"))
+	if xTraceId == "" {
+		return map[string]string{}
+	}
+	traceId := W3CTraceId(xTraceId)
+	spanId := SpanIdFromContext(ctx)
+	if spanId == "" {
+		spanId = NewSpanId()
+	}
+	sampled := unquote(t.Get("x_sampled")) != "0"
+	flags, b3Sampled := "00", "0"
+	if sampled {
+		flags, b3Sampled = "01", "1"
+	}
+
+	headers := map[string]string{
+		HeaderTraceparent: "00-" + traceId + "-" + spanId + "-" + flags,
+		HeaderB3:          traceId + "-" + spanId + "-" + b3Sampled,
+		HeaderB3TraceId:   traceId,
+		HeaderB3SpanId:    spanId,
+		HeaderB3Sampled:   b3Sampled,
+		HeaderTraceId:     xTraceId,
+	}
+	if state := unquote(t.Get("x_tracestate")); state != "" {
+		headers[HeaderTracestate] = state
+	}
+	if rpcId := unquote(t.Get("x_rpcid")); rpcId != "" {
+		headers[HeaderRpcId] = rpcId
+	}
+	return headers
+}
+
+// W3CTraceId 将x_trace_id转换为32位hex, uuid去掉横线, 其余格式取md5
+func W3CTraceId(xTraceId string) string {
+	raw := unquote(xTraceId)
+	id := strings.ToLower(strings.Replace(raw, "-", "", -1))
+	if isHex(id, 32) && id != strings.Repeat("0", 32) {
+		return id
+	}
+	if isHex(id, 16) {
+		return strings.Repeat("0", 16) + id
+	}
+	sum := md5.Sum([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+// NewSpanId 生成8字节随机span id
+func NewSpanId() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return strings.Repeat("0", 15) + "1"
+	}
+	return hex.EncodeToString(b)
+}
+
+// SpanIdFromContext 当前span的id, 尚未开启span时为空
+func SpanIdFromContext(ctx context.Context) string {
+	if span := SpanFromContext(ctx); span != nil {
+		return span.SpanId
+	}
+	return ""
+}
+
+func setTrace(t *TraceNode, traceId, parentSpanId string, sampled bool) {
+	t.Set("x_trace_id", quote(traceId))
+	if isHex(parentSpanId, 16) {
+		t.Set("x_parent_span_id", quote(parentSpanId))
+	}
+	if sampled {
+		t.Set("x_sampled", quote("1"))
+	} else {
+		t.Set("x_sampled", quote("0"))
+	}
+}
+
+// traceparent: version-traceid-parentid-flags
+func parseTraceparent(h string) (traceId, spanId string, sampled, ok bool) {
+	parts := strings.Split(strings.TrimSpace(strings.ToLower(h)), "-")
+	if len(parts) < 4 || !isHex(parts[0], 2) || parts[0] == "ff" {
+		return
+	}
+	if parts[0] == "00" && len(parts) != 4 {
+		return
+	}
+	if !isHex(parts[1], 32) || parts[1] == strings.Repeat("0", 32) ||
+		!isHex(parts[2], 16) || parts[2] == strings.Repeat("0", 16) || !isHex(parts[3], 2) {
+		return
+	}
+	flags, _ := hex.DecodeString(parts[3])
+	return parts[1], parts[2], flags[0]&0x01 == 0x01, true
+}
+
+// b3: traceid-spanid[-sampled[-parentspanid]], 仅有采样标记时视为无trace信息
+func parseB3Single(h string) (traceId, spanId string, sampled, ok bool) {
+	parts := strings.Split(strings.TrimSpace(strings.ToLower(h)), "-")
+	if len(parts) < 2 || !(isHex(parts[0], 16) || isHex(parts[0], 32)) || !isHex(parts[1], 16) {
+		return
+	}
+	sampled = true
+	if len(parts) > 2 && (parts[2] == "0") {
+		sampled = false
+	}
+	return parts[0], parts[1], sampled, true
+}
+
+func isHex(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	for _, c := range s {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func quote(v string) string {
+	return `"` + v + `"`
+}
+
+func unquote(v string) string {
+	return strings.Trim(v, `"`)
+}
@@ -0,0 +1,91 @@
+package logtrace
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractHeaders(t *testing.T) {
+	tests := []struct {
+		name        string
+		headers     map[string]string
+		wantOk      bool
+		wantTrace   string
+		wantParent  string
+		wantSampled string
+	}{
+		{
+			name: "traceparent",
+			headers: map[string]string{
+				HeaderTraceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+				HeaderTracestate:  "congo=t61rcWkgMzE",
+			},
+			wantOk:      true,
+			wantTrace:   `"4bf92f3577b34da6a3ce929d0e0e4736"`,
+			wantParent:  `"00f067aa0ba902b7"`,
+			wantSampled: `"1"`,
+		},
+		{
+			name: "b3 single",
+			headers: map[string]string{
+				HeaderB3: "80f198ee56343ba864fe8b2a57d3eff7-e457b5a2e4d86bd1-0",
+			},
+			wantOk:      true,
+			wantTrace:   `"80f198ee56343ba864fe8b2a57d3eff7"`,
+			wantParent:  `"e457b5a2e4d86bd1"`,
+			wantSampled: `"0"`,
+		},
+		{
+			name: "b3 multi",
+			headers: map[string]string{
+				HeaderB3TraceId: "463ac35c9f6413ad",
+				HeaderB3SpanId:  "a2fb4a1d1a96d312",
+			},
+			wantOk:      true,
+			wantTrace:   `"463ac35c9f6413ad"`,
+			wantParent:  `"a2fb4a1d1a96d312"`,
+			wantSampled: `"1"`,
+		},
+		{
+			name: "invalid traceparent",
+			headers: map[string]string{
+				HeaderTraceparent: "00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+			},
+			wantOk: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := NewTraceNode()
+			ok := ExtractHeaders(node, func(key string) string {
+				return tt.headers[key]
+			})
+			assert.Equal(t, tt.wantOk, ok)
+			assert.Equal(t, tt.wantTrace, node.Get("x_trace_id"))
+			assert.Equal(t, tt.wantParent, node.Get("x_parent_span_id"))
+			assert.Equal(t, tt.wantSampled, node.Get("x_sampled"))
+		})
+	}
+}
+
+func TestInjectHeaders(t *testing.T) {
+	node := GenLogTraceMetadata()
+	ctx := context.WithValue(context.Background(), GetMetadataKey(), node)
+	headers := InjectHeaders(ctx)
+
+	traceId := strings.Replace(strings.Trim(node.Get("x_trace_id"), `"`), "-", "", -1)
+	parts := strings.Split(headers[HeaderTraceparent], "-")
+	assert.Len(t, parts, 4)
+	assert.Equal(t, traceId, parts[1])
+	assert.Equal(t, traceId, headers[HeaderB3TraceId])
+	assert.Equal(t, "0.1", headers[HeaderRpcId])
+
+	back := NewTraceNode()
+	assert.True(t, ExtractHeaders(back, func(key string) string {
+		return headers[key]
+	}))
+	assert.Equal(t, `"`+traceId+`"`, back.Get("x_trace_id"))
+}
@@ -0,0 +1,188 @@
+package logtrace
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ytf606/golibs/errorx"
+)
+
+// SpanKind 与OpenTelemetry SpanKind取值一致
+type SpanKind int
+
+const (
+	SpanKindInternal SpanKind = iota + 1
+	SpanKindServer
+	SpanKindClient
+	SpanKindProducer
+	SpanKindConsumer
+)
+
+// StatusCode 与OpenTelemetry Status.Code取值一致
+type StatusCode int
+
+const (
+	StatusUnset StatusCode = iota
+	StatusOk
+	StatusError
+)
+
+// GetSpanKey context/gin.Context中保存当前span的key
+func GetSpanKey() string {
+	return "logtraceutil_span_key"
+}
+
+// Span 一次调用的耗时与父子关系, trace id取自TraceNode的x_trace_id
+type Span struct {
+	TraceId       string
+	SpanId        string
+	ParentSpanId  string
+	Name          string
+	Kind          SpanKind
+	StartTime     time.Time
+	EndTime       time.Time
+	Attributes    map[string]interface{}
+	StatusCode    StatusCode
+	StatusMessage string
+
+	sampled bool
+	ended   bool
+	lock    sync.Mutex
+}
+
+// StartSpan 以ctx中的当前span为父节点开启新span, 没有父span时使用上游透传的x_parent_span_id
+// 返回的ctx携带新span, 需调用span.End()结束
+func StartSpan(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	span := newSpan(ctx, name, kind)
+	return context.WithValue(ctx, GetSpanKey(), span), span
+}
+
+// StartSpanWith 用于无法派生context的场景(如gin.Context), 由调用方自行保存span
+func StartSpanWith(ctx context.Context, name string, kind SpanKind) *Span {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return newSpan(ctx, name, kind)
+}
+
+func newSpan(ctx context.Context, name string, kind SpanKind) *Span {
+	span := &Span{
+		SpanId:     NewSpanId(),
+		Name:       name,
+		Kind:       kind,
+		StartTime:  time.Now(),
+		Attributes: make(map[string]interface{}),
+		sampled:    true,
+	}
+	if parent := SpanFromContext(ctx); parent != nil {
+		span.TraceId = parent.TraceId
+		span.ParentSpanId = parent.SpanId
+		span.sampled = parent.sampled
+		return span
+	}
+	node, ok := ctx.Value(GetMetadataKey()).(*TraceNode)
+	if !ok || node == nil || node.Get("x_trace_id") == "" {
+		span.TraceId = W3CTraceId(NewTraceId())
+		return span
+	}
+	span.TraceId = W3CTraceId(node.Get("x_trace_id"))
+	span.ParentSpanId = unquote(node.Get("x_parent_span_id"))
+	span.sampled = unquote(node.Get("x_sampled")) != "0"
+	return span
+}
+
+// SpanFromContext 当前span, 没有时返回nil, nil上的方法均为空操作
+func SpanFromContext(ctx context.Context) *Span {
+	if ctx == nil {
+		return nil
+	}
+	span, _ := ctx.Value(GetSpanKey()).(*Span)
+	return span
+}
+
+func (s *Span) SetName(name string) *Span {
+	if s == nil {
+		return s
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.Name = name
+	return s
+}
+
+func (s *Span) SetAttribute(key string, value interface{}) *Span {
+	if s == nil {
+		return s
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.Attributes[key] = value
+	return s
+}
+
+func (s *Span) SetStatus(code StatusCode, message string) *Span {
+	if s == nil {
+		return s
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.StatusCode = code
+	s.StatusMessage = message
+	return s
+}
+
+// SetError 根据错误设置状态, errorx.Response记录业务码和http状态, 4xx视为调用方错误不标记span失败
+func (s *Span) SetError(err error) *Span {
+	if s == nil || err == nil {
+		return s
+	}
+	if res := errorx.UnWrapResponse(err); res != nil {
+		s.SetAttribute("errorx.code", res.Code)
+		if res.StatusCode > 0 {
+			s.SetAttribute("http.status_code", res.StatusCode)
+		}
+		if res.StatusCode >= 400 && res.StatusCode < 500 {
+			return s
+		}
+	}
+	return s.SetStatus(StatusError, err.Error())
+}
+
+// End 结束span并交给导出器, 重复调用只生效一次
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.lock.Lock()
+	if s.ended {
+		s.lock.Unlock()
+		return
+	}
+	s.ended = true
+	s.EndTime = time.Now()
+	sampled := s.sampled
+	s.lock.Unlock()
+
+	if sampled {
+		exportSpan(s)
+	}
+}
+
+func (s *Span) Duration() time.Duration {
+	if s == nil || s.EndTime.IsZero() {
+		return 0
+	}
+	return s.EndTime.Sub(s.StartTime)
+}
+
+func (s *Span) String() string {
+	if s == nil {
+		return ""
+	}
+	return fmt.Sprintf("%s %s/%s parent:%s cost:%v", s.Name, s.TraceId, s.SpanId, s.ParentSpanId, s.Duration())
+}
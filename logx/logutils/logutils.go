@@ -1,7 +1,9 @@
 package logutils
 
 import (
+	"context"
 	"strings"
+	"sync/atomic"
 
 	"github.com/ytf606/golibs/logx/log4go"
 )
@@ -11,6 +13,7 @@ var DefaultReplacer *strings.Replacer
 func init() {
 	DefaultReplacer = strings.NewReplacer("\t", "", "\r", "", "\n", "")
 	initLevelMap()
+	level.Store(Level)
 }
 
 func Filter(msg string, r ...string) string {
@@ -21,9 +24,13 @@ func Filter(msg string, r ...string) string {
 	return replacer.Replace(msg)
 }
 
+// Level 初始化时配置的日志级别, 运行时调整使用SetLevel
 var Level string = "ERROR"
 var SortLevel log4go.Level = 7
 
+// level 当前生效的日志级别
+var level atomic.Value
+
 var LevelMap map[string]log4go.Level
 var Inited bool
 
@@ -45,7 +52,7 @@ func ValidLevel(lvl string) bool {
 	if !Inited {
 		return true
 	}
-	if l, ok := LevelMap[Level]; !ok {
+	if l, ok := LevelMap[GetLevel()]; !ok {
 		return false
 	} else if l > LevelMap[lvl] {
 		return false
@@ -64,3 +71,57 @@ func LogLevel(lvl string) log4go.Level {
 	}
 	return l
 }
+
+// CtxLevelKey ctx中单个请求的日志级别, 如LoggerMiddleware开启的调试日志
+const CtxLevelKey = "logLevel"
+
+// CtxLevel ctx中设置的日志级别
+func CtxLevel(ctx context.Context) (log4go.Level, bool) {
+	if ctx == nil {
+		return 0, false
+	}
+	lvl, _ := ctx.Value(CtxLevelKey).(string)
+	l, ok := LevelMap[strings.ToUpper(lvl)]
+	return l, ok
+}
+
+// ValidLevelCtx ctx中设置了级别时以其为准, 单个请求可以调低或调高日志级别
+func ValidLevelCtx(ctx context.Context, lvl string) bool {
+	if !Inited {
+		return true
+	}
+	if l, ok := CtxLevel(ctx); ok {
+		return l <= LevelMap[lvl]
+	}
+	return ValidLevel(lvl)
+}
+
+// Escalated ctx中的级别低于当前生效的级别时返回该级别floor和当前级别gate, 用于log4go.LogFloor
+func Escalated(ctx context.Context) (floor, gate log4go.Level, ok bool) {
+	if !Inited {
+		return 0, 0, false
+	}
+	floor, ok = CtxLevel(ctx)
+	if !ok {
+		return 0, 0, false
+	}
+	gate = LevelMap[GetLevel()]
+	if floor >= gate {
+		return 0, 0, false
+	}
+	return floor, gate, true
+}
+
+// GetLevel 当前生效的日志级别
+func GetLevel() string {
+	return level.Load().(string)
+}
+
+// SetLevel 调整当前生效的日志级别, 可与日志输出并发调用
+func SetLevel(lvl string) bool {
+	if _, ok := LevelMap[lvl]; !ok {
+		return false
+	}
+	level.Store(lvl)
+	return true
+}
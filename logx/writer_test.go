@@ -0,0 +1,35 @@
+package logx
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/ytf606/golibs/logx/log4go"
+)
+
+// recordWriter 收集写入的日志, 供测试断言
+type recordWriter struct {
+	mu   sync.Mutex
+	recs []*log4go.LogRecord
+}
+
+func (w *recordWriter) LogWrite(rec *log4go.LogRecord) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.recs = append(w.recs, rec)
+}
+
+// count 消息以tag开头的日志条数
+func (w *recordWriter) count(tag string) int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	n := 0
+	for _, rec := range w.recs {
+		if strings.HasPrefix(rec.Message, tag+"\t") {
+			n++
+		}
+	}
+	return n
+}
+
+func (w *recordWriter) Close() {}
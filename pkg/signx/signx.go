@@ -0,0 +1,88 @@
+package signx
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// 签名相关的请求头
+const (
+	HeaderSource = "AUTH-SOURCE"
+	HeaderTime   = "AUTH-TIME"
+	HeaderNonce  = "AUTH-NONCE"
+	HeaderSign   = "AUTH-SIGN"
+)
+
+// BodyHash 请求体的sha256摘要, 空body同样参与计算
+func BodyHash(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// CanonicalQuery 按key、value排序后拼接query, 保证客户端与服务端顺序一致
+func CanonicalQuery(query url.Values) string {
+	if len(query) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var buf strings.Builder
+	for _, k := range keys {
+		values := append([]string(nil), query[k]...)
+		sort.Strings(values)
+		for _, v := range values {
+			if buf.Len() > 0 {
+				buf.WriteByte('&')
+			}
+			buf.WriteString(url.QueryEscape(k))
+			buf.WriteByte('=')
+			buf.WriteString(url.QueryEscape(v))
+		}
+	}
+	return buf.String()
+}
+
+// CanonicalString 待签名串: method、path、排序query、body摘要、时间戳、nonce, 以换行分隔
+func CanonicalString(method, path string, query url.Values, body []byte, timestamp, nonce string) string {
+	if path == "" {
+		path = "/"
+	}
+	return strings.Join([]string{
+		strings.ToUpper(method),
+		path,
+		CanonicalQuery(query),
+		BodyHash(body),
+		timestamp,
+		nonce,
+	}, "\n")
+}
+
+// Sign HMAC-SHA256(appKey, appId + "\n" + canonical), 返回hex编码
+func Sign(appId, appKey, canonical string) string {
+	mac := hmac.New(sha256.New, []byte(appKey))
+	mac.Write([]byte(appId + "\n" + canonical))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Equal 常量时间比较签名, 避免时序攻击
+func Equal(sign, expected string) bool {
+	return hmac.Equal([]byte(sign), []byte(expected))
+}
+
+// NewNonce 生成16字节随机nonce, 随机源失败时返回错误, 不能以空nonce发出请求
+func NewNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -1,6 +1,11 @@
 package request
 
+import "github.com/ytf606/golibs/errorx"
+
 const (
 	HttpDefaultTimeout    = 15
 	HttpDefaultRetryCount = 3
 )
+
+// HeaderRequestTimeout 透传给下游的剩余超时时间(毫秒)
+const HeaderRequestTimeout = errorx.HeaderRequestTimeout
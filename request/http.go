@@ -2,10 +2,12 @@ package request
 
 import (
 	"context"
+	"strconv"
 	"time"
 
 	"github.com/ytf606/golibs/errorx"
 	"github.com/ytf606/golibs/logx"
+	"github.com/ytf606/golibs/logx/logtrace"
 	"github.com/go-resty/resty/v2"
 )
 
@@ -20,6 +22,7 @@ var (
 type Requester interface {
 	SetTimeout(timeout int) Requester
 	SetRetryCount(count int) Requester
+	SetSigner(signer *Signer) Requester
 	GetClientInstance() *resty.Client
 	Get(ctx context.Context, url string, headers map[string]string) (res []byte, err error)
 	GetQuery(ctx context.Context, url string, query map[string]string, headers map[string]string) (res []byte, err error)
@@ -51,14 +54,21 @@ func (r *request) SetRetryCount(count int) Requester {
 	return r
 }
 
+func (r *request) SetSigner(signer *Signer) Requester {
+	r.client.SetPreRequestHook(signer.PreRequestHook)
+	return r
+}
+
 func (r *request) GetClientInstance() *resty.Client {
 	return r.client
 }
 
 func (r *request) Get(ctx context.Context, url string, headers map[string]string) (res []byte, err error) {
-	resp, err := r.client.R().
+	req := newRequest(ctx, r.client)
+	resp, err := req.
 		SetHeaders(headers).
 		Get(url)
+	endSpan(req, resp, err)
 	if err != nil {
 		logx.Ex(ctx, tag, "http request failed err:%+v, url:%s, headers:%+v",
 			err, url, headers)
@@ -74,12 +84,14 @@ func (r *request) Get(ctx context.Context, url string, headers map[string]string
 }
 
 func (r *request) GetQuery(ctx context.Context, url string, query map[string]string, headers map[string]string) (res []byte, err error) {
-	ins := r.client.R().
+	req := newRequest(ctx, r.client)
+	ins := req.
 		SetHeaders(headers)
 	if len(query) > 0 {
 		ins = ins.SetQueryParams(query)
 	}
 	resp, err := ins.Get(url)
+	endSpan(req, resp, err)
 	if err != nil {
 		logx.Ex(ctx, tag, "http request failed err:%+v, url:%s, headers:%+v",
 			err, url, headers)
@@ -95,10 +107,12 @@ func (r *request) GetQuery(ctx context.Context, url string, query map[string]str
 }
 
 func (r *request) PostForm(ctx context.Context, url string, body map[string]string, headers map[string]string) (res []byte, err error) {
-	resp, err := r.client.R().
+	req := newRequest(ctx, r.client)
+	resp, err := req.
 		SetHeaders(headers).
 		SetFormData(body).
 		Post(url)
+	endSpan(req, resp, err)
 	if err != nil {
 		logx.Ex(ctx, tag, "http request failed err:%+v, url:%s, headers:%+v, body:%+v",
 			err, url, headers, body)
@@ -114,11 +128,13 @@ func (r *request) PostForm(ctx context.Context, url string, body map[string]stri
 }
 
 func (r *request) PostRaw(ctx context.Context, url, body string, headers map[string]string) (res []byte, err error) {
-	resp, err := r.client.R().
+	req := newRequest(ctx, r.client)
+	resp, err := req.
 		SetHeader("Content-Type", "application/json").
 		SetHeaders(headers).
 		SetBody(body).
 		Post(url)
+	endSpan(req, resp, err)
 	if err != nil {
 		logx.Ex(ctx, tag, "http request failed err:%+v, url:%s, headers:%+v, body:%s", err, url, headers, body)
 		return nil, errorx.Wrap500Response(err, errorx.HttpRequestReturnErr, "")
@@ -133,9 +149,11 @@ func (r *request) PostRaw(ctx context.Context, url, body string, headers map[str
 }
 
 func Get(ctx context.Context, url string, headers map[string]string) (res []byte, err error) {
-	resp, err := HttpClient.R().
+	req := newRequest(ctx, HttpClient)
+	resp, err := req.
 		SetHeaders(headers).
 		Get(url)
+	endSpan(req, resp, err)
 	if err != nil {
 		logx.Ex(ctx, tag, "http request failed err:%+v, url:%s, headers:%+v",
 			err, url, headers)
@@ -151,10 +169,12 @@ func Get(ctx context.Context, url string, headers map[string]string) (res []byte
 }
 
 func PostForm(ctx context.Context, url string, body interface{}, headers map[string]string) (res []byte, err error) {
-	resp, err := HttpClient.R().
+	req := newRequest(ctx, HttpClient)
+	resp, err := req.
 		SetHeaders(headers).
 		SetBody(body).
 		Post(url)
+	endSpan(req, resp, err)
 	if err != nil {
 		logx.Ex(ctx, tag, "http request failed err:%+v, url:%s, headers:%+v, body:%+v",
 			err, url, headers, body)
@@ -170,11 +190,13 @@ func PostForm(ctx context.Context, url string, body interface{}, headers map[str
 }
 
 func PostRaw(ctx context.Context, url, body string, headers map[string]string) (res []byte, err error) {
-	resp, err := HttpClient.R().
+	req := newRequest(ctx, HttpClient)
+	resp, err := req.
 		SetHeader("Content-Type", "application/json").
 		SetHeaders(headers).
 		SetBody(body).
 		Post(url)
+	endSpan(req, resp, err)
 	if err != nil {
 		logx.Ex(ctx, tag, "http request failed err:%+v, url:%s, headers:%+v, body:%s", err, url, headers, body)
 		return nil, errorx.Wrap500Response(err, errorx.HttpRequestReturnErr, "")
@@ -187,3 +209,39 @@ func PostRaw(ctx context.Context, url, body string, headers map[string]string) (
 	}
 	return resp.Body(), nil
 }
+
+// newRequest 绑定ctx, 使调用随上游取消, 开启client span并透传trace与剩余超时时间
+func newRequest(ctx context.Context, client *resty.Client) *resty.Request {
+	req := client.R()
+	if ctx == nil {
+		return req
+	}
+	ctx, _ = logtrace.StartSpan(ctx, "HTTP", logtrace.SpanKindClient)
+	req.SetContext(ctx)
+	req.SetHeaders(logtrace.InjectHeaders(ctx))
+	if deadline, ok := ctx.Deadline(); ok {
+		req.SetHeader(HeaderRequestTimeout, strconv.FormatInt(time.Until(deadline).Milliseconds(), 10))
+	}
+	return req
+}
+
+// endSpan 记录请求结果并结束newRequest开启的span
+func endSpan(req *resty.Request, resp *resty.Response, err error) {
+	span := logtrace.SpanFromContext(req.Context())
+	if span == nil {
+		return
+	}
+	span.SetName("HTTP "+req.Method).
+		SetAttribute("http.method", req.Method).
+		SetAttribute("http.url", req.URL)
+	if resp != nil && resp.StatusCode() > 0 {
+		span.SetAttribute("http.status_code", resp.StatusCode())
+		if resp.StatusCode() >= 500 {
+			span.SetStatus(logtrace.StatusError, resp.Status())
+		}
+	}
+	if err != nil {
+		span.SetStatus(logtrace.StatusError, err.Error())
+	}
+	span.End()
+}
@@ -61,10 +61,17 @@ func (c *RpcxConfig) InitEtcdClient(ctx context.Context, servicePath string) (xc
 func (c *RpcxConfig) RpcxRequest(ctx context.Context, serviceName string, serviceMethod string, args interface{}) (*errorx.Response, error) {
 	tag := "[request_rpcx_RpcxRequest]"
 	reply := &errorx.Response{}
+	start := time.Now()
+	ctx, span := logtrace.StartSpan(ctx, serviceName+"/"+serviceMethod, logtrace.SpanKindClient)
+	span.SetAttribute("rpc.system", "rpcx").
+		SetAttribute("rpc.service", serviceName).
+		SetAttribute("rpc.method", serviceMethod)
+	defer span.End()
 	ctx = c.GenMetadata(ctx)
 	xc, err := c.InitEtcdClient(ctx, serviceName)
 	if err != nil {
 		logx.Ex(ctx, tag, "rpcx init etcd client failed err:%+v, serviceName:%s", err, serviceName)
+		span.SetError(err)
 		return nil, errorx.Wrap500Response(err, errorx.RpcEndpointErr, "")
 	}
 	tmCtx, cancel := context.WithTimeout(ctx, 15*time.Second)
@@ -74,12 +81,14 @@ func (c *RpcxConfig) RpcxRequest(ctx context.Context, serviceName string, servic
 	if err != nil {
 		logx.Ex(ctx, tag, "rpcx request selectClient failed err:%+v, serviceName:%s, serviceMethod:%s, args:%+v",
 			err, serviceName, serviceMethod, args)
+		span.SetError(err)
 		return nil, errorx.Wrap500Response(err, errorx.RpcEndpointErr, "")
 	}
 
 	replyCall := <-call.Done
 	if replyCall.Error != nil {
 		logx.Ex(ctx, tag, "rpcx request replyCall failed err:%+v", replyCall)
+		span.SetError(replyCall.Error)
 		if err := errorx.UnWrapResponse(replyCall.Error); err != nil {
 			return reply, err
 		}
@@ -93,6 +102,7 @@ func (c *RpcxConfig) RpcxRequest(ctx context.Context, serviceName string, servic
 		}
 		logx.Ex(ctx, tag, "rpcx request replyCode failed serviceName:%s, serviceMethod:%s, args:%+v, reply:%+v",
 			serviceName, serviceMethod, args, reply)
+		span.SetError(reply)
 		if err := errorx.UnWrapResponse(reply); err != nil {
 			return reply, err
 		}
@@ -101,12 +111,15 @@ func (c *RpcxConfig) RpcxRequest(ctx context.Context, serviceName string, servic
 				"rpcx request reply struct error serviceName:%s, serviceMethod:%s, args:%+v, reply:%+v",
 				serviceName, serviceMethod, args, reply)
 	}
+	logx.Dx(ctx, tag, "rpcx request return serviceName:%s, serviceMethod:%s, args:%+v, reply:%+v, cost:%v",
+		serviceName, serviceMethod, args, reply, time.Since(start))
 	return reply, nil
 }
 
 func (c *RpcxConfig) GenMetadata(ctx context.Context) context.Context {
 	var xRpcId string
 	var xTraceId string
+	traceHeaders := logtrace.InjectHeaders(ctx)
 	logTraceKey := ctx.Value(logtrace.GetMetadataKey())
 	if logTraceKey != nil {
 		xTraceId = logTraceKey.(*logtrace.TraceNode).Get("x_trace_id")
@@ -118,5 +131,10 @@ func (c *RpcxConfig) GenMetadata(ctx context.Context) context.Context {
 		"x_trace_id": xTraceId,
 		"x_rpcid":    xRpcId,
 	}
+	for _, k := range []string{logtrace.HeaderTraceparent, logtrace.HeaderTracestate, logtrace.HeaderB3} {
+		if v, ok := traceHeaders[k]; ok {
+			reqMetaData[k] = v
+		}
+	}
 	return context.WithValue(ctx, share.ReqMetaDataKey, reqMetaData)
 }
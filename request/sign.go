@@ -0,0 +1,71 @@
+package request
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/ytf606/golibs/pkg/signx"
+)
+
+// Signer 与middleware.CheckSignV2Middlerware配套的客户端签名
+type Signer struct {
+	Source string
+	AppID  string
+	AppKey string
+}
+
+func NewSigner(source, appId, appKey string) *Signer {
+	return &Signer{
+		Source: source,
+		AppID:  appId,
+		AppKey: appKey,
+	}
+}
+
+// Headers 为一次请求生成签名头, rawUrl需包含完整query
+func (s *Signer) Headers(method, rawUrl string, body []byte) (map[string]string, error) {
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return nil, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	nonce, err := signx.NewNonce()
+	if err != nil {
+		return nil, err
+	}
+	canonical := signx.CanonicalString(method, u.Path, u.Query(), body, timestamp, nonce)
+	return map[string]string{
+		signx.HeaderSource: s.Source,
+		signx.HeaderTime:   timestamp,
+		signx.HeaderNonce:  nonce,
+		signx.HeaderSign:   signx.Sign(s.AppID, s.AppKey, canonical),
+	}, nil
+}
+
+// PreRequestHook 在resty发出请求前签名, 每次重试都会生成新的nonce
+func (s *Signer) PreRequestHook(c *resty.Client, r *http.Request) error {
+	var body []byte
+	if r.GetBody != nil {
+		rc, err := r.GetBody()
+		if err != nil {
+			return err
+		}
+		body, err = ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	headers, err := s.Headers(r.Method, r.URL.String(), body)
+	if err != nil {
+		return err
+	}
+	for k, v := range headers {
+		r.Header.Set(k, v)
+	}
+	return nil
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 
+	"github.com/ytf606/golibs/logx/log4go"
+	"github.com/ytf606/golibs/logx/logutils"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 	"gorm.io/gorm/schema"
@@ -34,9 +36,12 @@ var (
 	ErrInvalidData = gorm.ErrInvalidData
 )
 
-// WithContext ...
+// WithContext 绑定ctx, 请求开启了调试日志(ctx中logLevel为DEBUG及以下)时该会话开启Debug输出sql
 func WithContext(ctx context.Context, db *gorm.DB) *gorm.DB {
-	db.WithContext(ctx)
+	db = db.WithContext(ctx)
+	if lvl, ok := logutils.CtxLevel(ctx); ok && lvl <= log4go.DEBUG {
+		db = db.Debug()
+	}
 	return db
 }
 
@@ -51,6 +56,10 @@ func Open(options *Config) (db *gorm.DB, err error) {
 		return nil, err
 	}
 
+	if err = db.Use(&tracePlugin{}); err != nil {
+		return nil, err
+	}
+
 	sqlDB, err := db.DB()
 	if err != nil {
 		return nil, err
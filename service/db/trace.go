@@ -0,0 +1,77 @@
+package db
+
+import (
+	"github.com/ytf606/golibs/logx/logtrace"
+	"gorm.io/gorm"
+)
+
+const traceSpanKey = "golibs:logtrace_span"
+
+type callbackRegister interface {
+	Register(name string, fn func(*gorm.DB)) error
+}
+
+// tracePlugin 为每次sql执行记录logtrace span, 需通过WithContext传入携带trace的ctx
+type tracePlugin struct{}
+
+func (p *tracePlugin) Name() string {
+	return "golibs:logtrace"
+}
+
+func (p *tracePlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	processors := map[string][2]callbackRegister{
+		"create": {cb.Create().Before("gorm:create"), cb.Create().After("gorm:create")},
+		"query":  {cb.Query().Before("gorm:query"), cb.Query().After("gorm:query")},
+		"update": {cb.Update().Before("gorm:update"), cb.Update().After("gorm:update")},
+		"delete": {cb.Delete().Before("gorm:delete"), cb.Delete().After("gorm:delete")},
+		"row":    {cb.Row().Before("gorm:row"), cb.Row().After("gorm:row")},
+		"raw":    {cb.Raw().Before("gorm:raw"), cb.Raw().After("gorm:raw")},
+	}
+	for op, p := range processors {
+		if err := p[0].Register("golibs:logtrace_before_"+op, traceBefore(op)); err != nil {
+			return err
+		}
+		if err := p[1].Register("golibs:logtrace_after_"+op, traceAfter); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func traceBefore(op string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		if db.Statement == nil || db.Statement.Context == nil {
+			return
+		}
+		span := logtrace.StartSpanWith(db.Statement.Context, "gorm."+op, logtrace.SpanKindClient)
+		span.SetAttribute("db.system", "mysql").
+			SetAttribute("db.operation", op)
+		if db.Statement.Table != "" {
+			span.SetAttribute("db.sql.table", db.Statement.Table)
+		}
+		db.InstanceSet(traceSpanKey, span)
+	}
+}
+
+func traceAfter(db *gorm.DB) {
+	val, ok := db.InstanceGet(traceSpanKey)
+	if !ok {
+		return
+	}
+	span, ok := val.(*logtrace.Span)
+	if !ok {
+		return
+	}
+	if db.Statement != nil {
+		span.SetAttribute("db.statement", db.Statement.SQL.String())
+		if db.Statement.Table != "" {
+			span.SetAttribute("db.sql.table", db.Statement.Table)
+		}
+	}
+	span.SetAttribute("db.rows_affected", db.RowsAffected)
+	if db.Error != nil && db.Error != gorm.ErrRecordNotFound {
+		span.SetError(db.Error)
+	}
+	span.End()
+}
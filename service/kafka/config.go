@@ -1,9 +1,11 @@
 package kafka
 
 import (
+	"context"
 	"time"
 
 	"github.com/Shopify/sarama"
+	"github.com/ytf606/golibs/logx/logtrace"
 )
 
 const (
@@ -35,10 +37,16 @@ type Message struct {
 	Offset    int64
 	Key       []byte
 	Value     []byte
+	// 消息header, 已用于Context()延续生产端trace
+	Headers map[string]string
 
 	// If not set at the creation, Time will be automatically set when
 	// writing the message.
 	Time time.Time
+
+	// ctx 携带生产端trace和kafka.consume span
+	ctx  context.Context
+	span *logtrace.Span
 }
 
 type ProducerMessage struct {
@@ -7,6 +7,7 @@ import (
 
 	"github.com/ytf606/golibs/errorx"
 	"github.com/ytf606/golibs/logx"
+	"github.com/ytf606/golibs/logx/logtrace"
 	"github.com/Shopify/sarama"
 	cluster "github.com/bsm/sarama-cluster"
 )
@@ -134,6 +135,7 @@ func (this *consumer) CommitMessage(ctx context.Context, mess ...*Message) error
 	}
 	if len(mess) > 0 {
 		for _, msg := range mess {
+			msg.span.End()
 			this.clusterReader.MarkOffset(&sarama.ConsumerMessage{
 				Key:       msg.Key,
 				Value:     msg.Value,
@@ -180,17 +182,49 @@ func (this *consumer) initReader(ctx context.Context) (err error) {
 	return nil
 }
 
+// Context 携带生产端trace和kafka.consume span, 处理消息时的日志和下游调用需使用该ctx
+func (m *Message) Context() context.Context {
+	if m.ctx == nil {
+		return context.Background()
+	}
+	return m.ctx
+}
+
+// Handle 在kafka.consume span内执行fn, fn返回后记录错误并结束span
+// 未使用Handle时, span在CommitMessage时结束
+func (m *Message) Handle(fn func(ctx context.Context, msg *Message) error) error {
+	err := fn(m.Context(), m)
+	m.span.SetError(err)
+	m.span.End()
+	return err
+}
+
 func (this *consumer) tranMessage(ok bool, msg *sarama.ConsumerMessage, err error) {
 	if msg == nil {
 		return
 	}
 	if ok {
+		headers := make(map[string]string, len(msg.Headers))
+		for _, h := range msg.Headers {
+			if h != nil {
+				headers[string(h.Key)] = string(h.Value)
+			}
+		}
+		ctx := logtrace.AppendLogTraceMetadataContext(context.Background(), headers)
+		ctx, span := logtrace.StartSpan(ctx, "kafka.consume "+msg.Topic, logtrace.SpanKindConsumer)
+		span.SetAttribute("messaging.system", "kafka").
+			SetAttribute("messaging.destination", msg.Topic).
+			SetAttribute("messaging.kafka.partition", int(msg.Partition)).
+			SetAttribute("messaging.kafka.offset", msg.Offset)
 		this.messages <- &Message{
+			ctx:       ctx,
+			span:      span,
 			Topic:     msg.Topic,
 			Partition: int(msg.Partition),
 			Offset:    msg.Offset,
 			Key:       msg.Key,
 			Value:     msg.Value,
+			Headers:   headers,
 			Time:      msg.BlockTimestamp,
 		}
 	} else {
@@ -0,0 +1,43 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/ytf606/golibs/logx/logtrace"
+)
+
+func TestConsumeSpan(t *testing.T) {
+	c := &consumer{messages: make(chan *Message, 1)}
+	c.tranMessage(true, &sarama.ConsumerMessage{
+		Topic: "order",
+		Value: []byte("v"),
+		Headers: []*sarama.RecordHeader{
+			{Key: []byte(logtrace.HeaderTraceparent), Value: []byte("00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")},
+		},
+	}, nil)
+	msg := <-c.messages
+
+	span := logtrace.SpanFromContext(msg.Context())
+	if !assert.NotNil(t, span) {
+		return
+	}
+	assert.Equal(t, "0af7651916cd43dd8448eb211c80319c", span.TraceId)
+
+	var child *logtrace.Span
+	err := msg.Handle(func(ctx context.Context, m *Message) error {
+		_, child = logtrace.StartSpan(ctx, "handle", logtrace.SpanKindInternal)
+		child.End()
+		time.Sleep(5 * time.Millisecond)
+		return errors.New("failed")
+	})
+	assert.EqualError(t, err, "failed")
+	assert.Equal(t, span.SpanId, child.ParentSpanId)
+	assert.Equal(t, span.TraceId, child.TraceId)
+	assert.GreaterOrEqual(t, int64(span.Duration()), int64(5*time.Millisecond))
+	assert.Equal(t, logtrace.StatusError, span.StatusCode)
+}
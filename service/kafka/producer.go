@@ -8,6 +8,7 @@ import (
 
 	"github.com/ytf606/golibs/errorx"
 	"github.com/ytf606/golibs/logx"
+	"github.com/ytf606/golibs/logx/logtrace"
 	"github.com/Shopify/sarama"
 )
 
@@ -38,7 +39,8 @@ func NewProducer(ctx context.Context, config *ProducerConfig) (Producer, error)
 	}
 	var err error
 	if config.Async {
-		mqConfig.Producer.Return.Successes = false
+		// 异步模式下也需回传成功消息, 用于在broker确认后结束produce span
+		mqConfig.Producer.Return.Successes = true
 		Producter.asyncProducer, err = sarama.NewAsyncProducer(config.Brokers, mqConfig)
 	} else {
 		mqConfig.Producer.Return.Successes = true
@@ -50,6 +52,9 @@ func NewProducer(ctx context.Context, config *ProducerConfig) (Producer, error)
 		return nil, errorx.Wrap500Response(err, errorx.KafkaInitProducerErr, "")
 	}
 	Producter.kafkaConfig = mqConfig
+	if config.Async {
+		go Producter.handleSuccesses()
+	}
 	go Producter.CleanErrorChan(ctx)
 	return Producter, nil
 }
@@ -87,7 +92,10 @@ func (this *producer) SyncWriter(ctx context.Context, mess ...*ProducerMessage)
 		if msg == nil {
 			continue
 		}
+		span := startProduceSpan(ctx, msg)
 		partition, offset, err := this.syncProducer.SendMessage(msg)
+		span.SetAttribute("messaging.kafka.partition", int(partition))
+		span.SetError(err).End()
 		if err != nil {
 			logx.Ex(ctx, tag, "SyncWriter failed err:%+v", err)
 			rerr = errorx.Wrap500Response(err, errorx.KafkaProducerWriterErr, "")
@@ -132,11 +140,24 @@ func (this *producer) AsyncWriter(ctx context.Context, mess ...*ProducerMessage)
 		if msg == nil {
 			continue
 		}
+		// span在Successes/Errors回传时结束, 覆盖实际投递耗时
+		msg.Metadata = startProduceSpan(ctx, msg)
 		pchan <- msg
 	}
 	return nil
 }
 
+// handleSuccesses 消费异步producer的成功回传并结束对应的produce span
+func (this *producer) handleSuccesses() {
+	for msg := range this.asyncProducer.Successes() {
+		if span, ok := msg.Metadata.(*logtrace.Span); ok {
+			span.SetAttribute("messaging.kafka.partition", int(msg.Partition)).
+				SetAttribute("messaging.kafka.offset", msg.Offset).
+				End()
+		}
+	}
+}
+
 func (this *producer) Errors(ctx context.Context) <-chan *Error {
 	tag := "[kafka_produce_Errors]"
 	if !this.producterConfig.Async {
@@ -154,10 +175,16 @@ func (this *producer) Errors(ctx context.Context) <-chan *Error {
 			for {
 				//perrChan := this.asyncProducer.Errors()
 				select {
-				case pe := <-this.asyncProducer.Errors():
+				case pe, ok := <-this.asyncProducer.Errors():
+					if !ok {
+						return
+					}
 					if pe == nil {
 						continue
 					}
+					if span, ok := pe.Msg.Metadata.(*logtrace.Span); ok {
+						span.SetError(pe.Err).End()
+					}
 					mqerr := &Error{
 						Topic:     pe.Msg.Topic,
 						Partition: int(pe.Msg.Partition),
@@ -210,3 +237,21 @@ func (this *producer) formatMessage(mess ...*ProducerMessage) []*sarama.Producer
 	}
 	return megs
 }
+
+// startProduceSpan 开启producer span, 并将trace信息写入消息header供消费端延续
+func startProduceSpan(ctx context.Context, msg *sarama.ProducerMessage) *logtrace.Span {
+	ctx, span := logtrace.StartSpan(ctx, "kafka.produce "+msg.Topic, logtrace.SpanKindProducer)
+	span.SetAttribute("messaging.system", "kafka").
+		SetAttribute("messaging.destination", msg.Topic)
+	headers := logtrace.InjectHeaders(ctx)
+	if v, ok := headers[logtrace.HeaderTraceId]; ok {
+		headers["x_trace_id"] = v
+	}
+	if v, ok := headers[logtrace.HeaderRpcId]; ok {
+		headers["x_rpcid"] = v
+	}
+	for k, v := range headers {
+		msg.Headers = append(msg.Headers, sarama.RecordHeader{Key: []byte(k), Value: []byte(v)})
+	}
+	return span
+}
@@ -0,0 +1,72 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/Shopify/sarama/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/ytf606/golibs/logx/logtrace"
+)
+
+// recordProducer 记录写入Input的消息, 便于取回其produce span
+type recordProducer struct {
+	*mocks.AsyncProducer
+	input chan *sarama.ProducerMessage
+	sent  []*sarama.ProducerMessage
+}
+
+func (r *recordProducer) Input() chan<- *sarama.ProducerMessage {
+	return r.input
+}
+
+func TestAsyncProduceSpan(t *testing.T) {
+	config := mocks.NewTestConfig()
+	config.Producer.Return.Successes = true
+	config.Producer.Return.Errors = true
+	mp := mocks.NewAsyncProducer(t, config)
+	mp.ExpectInputAndSucceed()
+	mp.ExpectInputAndFail(errors.New("failed"))
+
+	rp := &recordProducer{AsyncProducer: mp, input: make(chan *sarama.ProducerMessage)}
+	go func() {
+		for msg := range rp.input {
+			rp.sent = append(rp.sent, msg)
+			mp.Input() <- msg
+		}
+	}()
+	p := &producer{
+		asyncProducer:   rp,
+		producterConfig: &ProducerConfig{Async: true, Topic: "order"},
+		errors:          make(chan *Error, 2),
+	}
+	done := make(chan struct{})
+	go func() {
+		p.handleSuccesses()
+		close(done)
+	}()
+	errs := p.Errors(context.Background())
+
+	ctx, root := logtrace.StartSpan(context.Background(), "root", logtrace.SpanKindInternal)
+	defer root.End()
+	assert.Nil(t, p.AsyncWriter(ctx, &ProducerMessage{Value: []byte("ok")}, &ProducerMessage{Value: []byte("bad")}))
+	close(rp.input)
+
+	mqerr := <-errs
+	assert.Equal(t, "order", mqerr.Topic)
+	assert.Nil(t, mp.Close())
+	<-done
+
+	if !assert.Len(t, rp.sent, 2) {
+		return
+	}
+	ok := rp.sent[0].Metadata.(*logtrace.Span)
+	assert.Equal(t, root.SpanId, ok.ParentSpanId)
+	assert.False(t, ok.EndTime.IsZero())
+	assert.NotEqual(t, logtrace.StatusError, ok.StatusCode)
+	bad := rp.sent[1].Metadata.(*logtrace.Span)
+	assert.False(t, bad.EndTime.IsZero())
+	assert.Equal(t, logtrace.StatusError, bad.StatusCode)
+}
@@ -79,6 +79,7 @@ func (r *RedisManager) Init(configs ...Config) error {
 			PoolSize:     v.PoolSize,
 			MinIdleConns: 3,
 		})
+		newc.AddHook(&traceHook{name: v.Name})
 		res, err := newc.Ping(context.Background()).Result()
 		if err != nil || res != "PONG" {
 			return fmt.Errorf("ping redis [%s] failed, error:%s", v.Addr, err.Error())
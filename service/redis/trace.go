@@ -0,0 +1,52 @@
+package redis
+
+import (
+	"context"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/ytf606/golibs/logx/logtrace"
+)
+
+// traceHook 为每条redis命令记录logtrace span
+type traceHook struct {
+	name string
+}
+
+var _ redis.Hook = (*traceHook)(nil)
+
+func (h *traceHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	ctx, span := logtrace.StartSpan(ctx, "redis."+cmd.Name(), logtrace.SpanKindClient)
+	span.SetAttribute("db.system", "redis").
+		SetAttribute("db.redis.instance", h.name).
+		SetAttribute("db.operation", cmd.Name())
+	return ctx, nil
+}
+
+func (h *traceHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	span := logtrace.SpanFromContext(ctx)
+	if err := cmd.Err(); err != nil && err != redis.Nil {
+		span.SetError(err)
+	}
+	span.End()
+	return nil
+}
+
+func (h *traceHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	ctx, span := logtrace.StartSpan(ctx, "redis.pipeline", logtrace.SpanKindClient)
+	span.SetAttribute("db.system", "redis").
+		SetAttribute("db.redis.instance", h.name).
+		SetAttribute("db.redis.num_cmd", len(cmds))
+	return ctx, nil
+}
+
+func (h *traceHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	span := logtrace.SpanFromContext(ctx)
+	for _, cmd := range cmds {
+		if err := cmd.Err(); err != nil && err != redis.Nil {
+			span.SetError(err)
+			break
+		}
+	}
+	span.End()
+	return nil
+}